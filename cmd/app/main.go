@@ -17,6 +17,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/bot"
 	"github.com/imhuimie/let-monitor-go/internal/config"
 	"github.com/imhuimie/let-monitor-go/internal/database"
 	"github.com/imhuimie/let-monitor-go/internal/monitor"
@@ -81,8 +82,13 @@ func main() {
 	mon.Start()
 	defer mon.Stop()
 
+	// Interactive Telegram bot, idle unless telegram_bot_enabled is set
+	tgBot := bot.New(cfgMgr, mon, db)
+	tgBot.Start()
+	defer tgBot.Stop()
+
 	// Create and start web server
-	srv := server.NewServer(cfgMgr, mon, accessToken, port)
+	srv := server.NewServer(cfgMgr, mon, db, accessToken, port)
 
 	// Start server in goroutine
 	go func() {
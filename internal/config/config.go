@@ -12,10 +12,19 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/filter/expr"
+	"github.com/imhuimie/let-monitor-go/internal/filter/prompt"
+	"github.com/imhuimie/let-monitor-go/internal/notifier/webhook"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -26,14 +35,43 @@ type Config struct {
 	OnlyExtra     bool     `json:"only_extra"`
 	Frequency     int      `json:"frequency"`      // in seconds
 	CommentFilter string   `json:"comment_filter"` // "by_role" or "by_author"
+	CommentRoles  []string `json:"comment_roles"`  // roles accepted by "by_role"
+	MutedThreads  []string `json:"muted_threads"`  // thread URLs whose new comments are not notified
+
+	// Scheduling
+	SourceFrequency map[string]int `json:"source_frequency"` // per-source interval in seconds, keyed by URL
+	MaxWorkers      int            `json:"max_workers"`      // concurrent source checks
+	HostRateLimit   int            `json:"host_rate_limit"`  // minimum interval between requests to one host, in milliseconds
 
 	// Keyword filter
-	UseKeywordsFilter bool   `json:"use_keywords_filter"`
-	KeywordsRule      string `json:"keywords_rule"`
+	UseKeywordsFilter   bool   `json:"use_keywords_filter"`
+	KeywordsRule        string `json:"keywords_rule"`         // legacy comment rule, used when comment_keywords_rule is empty
+	ThreadKeywordsRule  string `json:"thread_keywords_rule"`  // matched against thread title and description; empty disables thread keyword filtering
+	CommentKeywordsRule string `json:"comment_keywords_rule"` // matched against comment messages
+
+	// How keyword and AI filters combine when both are enabled: "and" requires
+	// both to pass, "or" notifies on a keyword match without calling the AI
+	FilterMode string `json:"filter_mode"`
 
 	// AI filter
 	UseAIFilter bool   `json:"use_ai_filter"`
-	AIProvider  string `json:"ai_provider"` // "cloudflare" or "openai"
+	AIProvider  string `json:"ai_provider"` // "cloudflare", "openai", "anthropic" or "ollama"
+	// "text" keeps the FALSE/END reply protocol, "json" asks for a structured verdict
+	AIOutputMode string `json:"ai_output_mode"`
+	// Hours an AI result is reused for identical prompt and content, 0 disables the cache
+	AICacheTTL int `json:"ai_cache_ttl"`
+	// Providers tried in order until one answers; empty uses ai_provider alone
+	AIProviders []string `json:"ai_providers"`
+	// Consecutive failures after which a provider is skipped for ai_breaker_cooldown seconds
+	AIBreakerThreshold int `json:"ai_breaker_threshold"`
+	AIBreakerCooldown  int `json:"ai_breaker_cooldown"`
+	// What to do when every provider fails: "notify", "hold" or "drop"
+	AIFailurePolicy string `json:"ai_failure_policy"`
+	// Prices per model name used to estimate the cost of AI requests
+	AIPricing map[string]AIPrice `json:"ai_pricing"`
+	// Daily limits (UTC days) after which only the keyword filter runs; 0 is unlimited
+	AIDailyTokenBudget int64   `json:"ai_daily_token_budget"`
+	AIDailyCostBudget  float64 `json:"ai_daily_cost_budget"` // USD
 
 	// Cloudflare AI settings
 	CFAccountID string `json:"cf_account_id"`
@@ -45,18 +83,242 @@ type Config struct {
 	OpenAIAPIKey string `json:"openai_api_key"`
 	OpenAIModel  string `json:"openai_model"`
 
-	// AI prompts (shared by both providers)
+	// Anthropic Messages API settings
+	AnthropicAPIURL    string `json:"anthropic_api_url"`
+	AnthropicAPIKey    string `json:"anthropic_api_key"`
+	AnthropicModel     string `json:"anthropic_model"`
+	AnthropicMaxTokens int    `json:"anthropic_max_tokens"` // upper bound of the reply length
+
+	// Ollama settings, no API key needed
+	OllamaURL   string `json:"ollama_url"` // base URL, e.g. http://localhost:11434
+	OllamaModel string `json:"ollama_model"`
+
+	// AI prompts (shared by all providers), Go templates over prompt.Data
 	ThreadPrompt  string `json:"thread_prompt"`
 	CommentPrompt string `json:"comment_prompt"`
 
 	// Notification
-	NoticeType  string `json:"notice_type"` // "telegram", "wechat", "custom"
+	NoticeType  string `json:"notice_type"` // one of NoticeTypes
 	TelegramBot string `json:"telegrambot"`
 	ChatID      string `json:"chat_id"`
 	WeChatKey   string `json:"wechat_key"`
 	CustomURL   string `json:"custom_url"`
 
-	mu sync.RWMutex
+	// Incoming webhook URLs of the discord, slack and teams notice types
+	DiscordWebhook string `json:"discord_webhook"`
+	SlackWebhook   string `json:"slack_webhook"`
+	TeamsWebhook   string `json:"teams_webhook"`
+
+	// Request of custom notifications; empty sends the legacy GET to custom_url
+	Webhook WebhookConfig `json:"webhook"`
+
+	// More telegram chats receiving the same notifications as chat_id. Like
+	// chat_id, an entry may name a forum topic as "chat_id:message_thread_id".
+	ChatIDs []string `json:"chat_ids"`
+
+	// Messages per minute sent to one telegram chat, also the default of
+	// telegram channels. Telegram allows about 20 per minute in groups.
+	TelegramRateLimit int `json:"telegram_rate_limit"`
+
+	// Telegram message formatting, also the default of telegram channels
+	TelegramParseMode      string `json:"telegram_parse_mode"`      // "html", "markdownv2" or "plain"
+	TelegramDisablePreview bool   `json:"telegram_disable_preview"` // no link preview under messages
+
+	// Interactive bot polling getUpdates with telegrambot, answering only chat_id
+	TelegramBotEnabled bool `json:"telegram_bot_enabled"`
+	// User IDs allowed to run bot commands that change state; when empty,
+	// administrators of the chat are allowed
+	TelegramBotAdmins []int64 `json:"telegram_bot_admins"`
+
+	// Multiple notification channels; when empty the legacy single channel
+	// described by notice_type and the fields above is used
+	Channels []ChannelConfig `json:"channels"`
+}
+
+// Filter modes combining the keyword and AI filters
+const (
+	FilterModeAnd = "and"
+	FilterModeOr  = "or"
+)
+
+// AI output modes
+const (
+	AIOutputText = "text" // summary text ending with END, or FALSE to reject
+	AIOutputJSON = "json" // JSON verdict, see filter.ParseVerdict
+)
+
+// AI failure policies, applied when no AI provider answers
+const (
+	AIFailureNotify = "notify" // notify unfiltered
+	AIFailureHold   = "hold"   // keep the item and re-evaluate it later
+	AIFailureDrop   = "drop"   // do not notify
+)
+
+// Telegram parse modes
+const (
+	TelegramParseHTML       = "html"
+	TelegramParseMarkdownV2 = "markdownv2"
+	TelegramParsePlain      = "plain" // unformatted text, as before parse modes were supported
+)
+
+// NoticeTypes lists the supported notification types
+var NoticeTypes = []string{"telegram", "wechat", "custom", "discord", "slack", "teams"}
+
+// AIProviderNames lists the supported AI providers
+var AIProviderNames = []string{"cloudflare", "openai", "anthropic", "ollama"}
+
+// Default endpoints of the Anthropic and Ollama providers
+const (
+	DefaultAnthropicAPIURL    = "https://api.anthropic.com/v1/messages"
+	DefaultAnthropicMaxTokens = 1024
+	DefaultOllamaURL          = "http://localhost:11434"
+)
+
+// DefaultTelegramRateLimit is the default telegram_rate_limit
+const DefaultTelegramRateLimit = 20
+
+// maxWorkers is the largest accepted max_workers
+const maxWorkers = 32
+
+// AIPrice is the price of a model in USD per million tokens
+type AIPrice struct {
+	Input  float64 `json:"input"`  // prompt tokens
+	Output float64 `json:"output"` // completion tokens
+}
+
+// Cost returns the cost in USD of a request with the given token counts
+func (p AIPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// ChannelConfig describes one notification channel
+type ChannelConfig struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"` // one of NoticeTypes
+	Disabled    bool          `json:"disabled"`
+	TelegramBot string        `json:"telegrambot"`
+	ChatID      string        `json:"chat_id"`
+	ChatIDs     []string      `json:"chat_ids"`
+	WeChatKey   string        `json:"wechat_key"`
+	CustomURL   string        `json:"custom_url"`
+	Webhook     WebhookConfig `json:"webhook"`
+	Rules       ChannelRules  `json:"rules"`
+
+	// Incoming webhook URL of a discord, slack or teams channel
+	DiscordWebhook string `json:"discord_webhook"`
+	SlackWebhook   string `json:"slack_webhook"`
+	TeamsWebhook   string `json:"teams_webhook"`
+
+	// Messages per minute sent to one chat; 0 uses telegram_rate_limit
+	RateLimit int `json:"rate_limit"`
+
+	// Telegram formatting; an empty parse mode uses telegram_parse_mode
+	ParseMode      string `json:"parse_mode"`
+	DisablePreview bool   `json:"disable_preview"`
+
+	// MuteButton adds a "mute this thread" button to telegram messages. It is
+	// set by NotificationChannels when the interactive bot receives the
+	// button presses, i.e. the channel uses the bot's token.
+	MuteButton bool `json:"-"`
+}
+
+// WebhookConfig configures the HTTP request of a custom notification. Without
+// a body, a GET request is sent to custom_url with {message} replaced by the
+// URL-encoded notification text, as before bodies were supported.
+type WebhookConfig struct {
+	Method          string            `json:"method"`           // GET by default, POST when a body is set
+	Headers         map[string]string `json:"headers"`          // extra request headers
+	Body            string            `json:"body"`             // Go template over webhook.Data
+	BodyType        string            `json:"body_type"`        // "json" (default), "form" or "text"
+	Secret          string            `json:"secret"`           // signs the body with HMAC-SHA256 when set
+	SignatureHeader string            `json:"signature_header"` // header of the signature, X-Signature-256 by default
+	Timeout         int               `json:"timeout"`          // seconds, 10 by default
+	Retries         int               `json:"retries"`          // extra attempts after network errors, 429 and 5xx
+}
+
+// Validate validates a webhook request and fills its defaults
+func (w *WebhookConfig) Validate() error {
+	w.Method = strings.ToUpper(w.Method)
+	if w.Method == "" {
+		w.Method = http.MethodGet
+		if w.Body != "" {
+			w.Method = http.MethodPost
+		}
+	}
+	switch w.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("webhook.method 必须是 GET、POST、PUT 或 PATCH")
+	}
+	if w.Method == http.MethodGet && w.Body != "" {
+		return fmt.Errorf("GET 请求不能设置 webhook.body")
+	}
+
+	for name := range w.Headers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("webhook.headers 的名称不能为空")
+		}
+	}
+
+	if w.BodyType == "" {
+		w.BodyType = webhook.BodyJSON
+	}
+	if w.BodyType != webhook.BodyJSON && w.BodyType != webhook.BodyForm && w.BodyType != webhook.BodyText {
+		return fmt.Errorf("webhook.body_type 必须是 'json'、'form' 或 'text'")
+	}
+	if w.Body != "" {
+		if _, err := webhook.Parse(w.Body, w.BodyType); err != nil {
+			return fmt.Errorf("webhook.body 无效: %w", err)
+		}
+	}
+
+	if w.Secret != "" && w.Body == "" {
+		return fmt.Errorf("webhook.secret 对请求体签名，需要同时设置 webhook.body")
+	}
+	if w.SignatureHeader == "" {
+		w.SignatureHeader = webhook.DefaultSignatureHeader
+	}
+
+	// Headers derived from the body may not be replaced
+	for name := range w.Headers {
+		switch {
+		case w.Body != "" && strings.EqualFold(name, "Content-Type"):
+			return fmt.Errorf("webhook.headers 不能包含 Content-Type，请求体类型由 webhook.body_type 决定")
+		case w.Secret != "" && strings.EqualFold(name, w.SignatureHeader):
+			return fmt.Errorf("webhook.headers 不能包含签名头 %s", w.SignatureHeader)
+		}
+	}
+
+	if w.Timeout == 0 {
+		w.Timeout = 10
+	}
+	if w.Timeout < 1 || w.Timeout > 120 {
+		return fmt.Errorf("webhook.timeout 必须在 1 到 120 秒之间")
+	}
+	if w.Retries < 0 || w.Retries > 5 {
+		return fmt.Errorf("webhook.retries 必须在 0 到 5 之间")
+	}
+	return nil
+}
+
+// ChannelRules restricts which notifications are routed to a channel.
+// Empty fields match everything.
+type ChannelRules struct {
+	Kinds      []string `json:"kinds"`      // "thread" and/or "comment"
+	Domains    []string `json:"domains"`    // e.g. "lowendtalk", matched as substring of the thread domain
+	Categories []string `json:"categories"` // e.g. "offers"
+	Keywords   string   `json:"keywords"`   // keyword rule in keywords_rule syntax
+}
+
+// DefaultCommentRoles are the forum roles accepted by the "by_role" comment filter
+// when comment_roles is not configured
+var DefaultCommentRoles = []string{
+	"Provider",
+	"Top Provider",
+	"Patron Provider",
+	"Host Rep",
+	"Administrator",
+	"Moderator",
 }
 
 // ConfigWrapper wraps the config with a "config" key
@@ -68,9 +330,17 @@ type ConfigWrapper struct {
 type Manager struct {
 	configPath string
 	config     *Config
+	revision   uint64 // incremented whenever config is replaced
 	mu         sync.RWMutex
 }
 
+var (
+	// ErrInvalid is wrapped by update errors caused by a config that fails Validate
+	ErrInvalid = errors.New("配置验证失败")
+	// ErrStale is returned by UpdateAt when the config changed since the given revision
+	ErrStale = errors.New("配置已被其他地方修改，请重新加载后再保存")
+)
+
 // NewManager creates a new configuration manager
 func NewManager(configPath string) *Manager {
 	return &Manager{
@@ -107,7 +377,17 @@ func (m *Manager) Load() error {
 		return fmt.Errorf("配置文件格式错误: 缺少 'config' 键")
 	}
 
+	// The config file may be edited by hand, so rules and channel names are
+	// checked here too
+	if err := wrapper.Config.validateKeywordRules(); err != nil {
+		return fmt.Errorf("配置文件错误: %w", err)
+	}
+	if err := wrapper.Config.nameChannels(); err != nil {
+		return fmt.Errorf("配置文件错误: %w", err)
+	}
+
 	m.config = wrapper.Config
+	m.revision++
 
 	// Set default values
 	if m.config.Frequency == 0 {
@@ -116,12 +396,51 @@ func (m *Manager) Load() error {
 	if m.config.CommentFilter == "" {
 		m.config.CommentFilter = "by_role"
 	}
+	if len(m.config.CommentRoles) == 0 {
+		m.config.CommentRoles = append([]string(nil), DefaultCommentRoles...)
+	}
+	if m.config.MaxWorkers == 0 {
+		m.config.MaxWorkers = 4
+	}
+	if m.config.MaxWorkers < 1 || m.config.MaxWorkers > maxWorkers {
+		clamped := min(max(m.config.MaxWorkers, 1), maxWorkers)
+		log.Warnf("max_workers 必须在 1 到 %d 之间，已将 %d 改为 %d", maxWorkers, m.config.MaxWorkers, clamped)
+		m.config.MaxWorkers = clamped
+	}
+	if m.config.HostRateLimit == 0 {
+		m.config.HostRateLimit = 1000
+	}
+	if m.config.HostRateLimit < 0 {
+		log.Warnf("host_rate_limit 必须至少为 1 毫秒，已将 %d 改为 1000", m.config.HostRateLimit)
+		m.config.HostRateLimit = 1000
+	}
 	if m.config.NoticeType == "" {
 		m.config.NoticeType = "telegram"
 	}
+	if m.config.FilterMode == "" {
+		m.config.FilterMode = FilterModeAnd
+	}
+	if m.config.TelegramParseMode == "" {
+		m.config.TelegramParseMode = TelegramParseHTML
+	}
+	if m.config.TelegramRateLimit == 0 {
+		m.config.TelegramRateLimit = DefaultTelegramRateLimit
+	}
 	if m.config.AIProvider == "" {
 		m.config.AIProvider = "cloudflare"
 	}
+	if m.config.AIOutputMode == "" {
+		m.config.AIOutputMode = AIOutputText
+	}
+	if m.config.AIBreakerThreshold == 0 {
+		m.config.AIBreakerThreshold = 3
+	}
+	if m.config.AIBreakerCooldown == 0 {
+		m.config.AIBreakerCooldown = 300
+	}
+	if m.config.AIFailurePolicy == "" {
+		m.config.AIFailurePolicy = AIFailureNotify
+	}
 
 	log.Info("配置文件加载成功")
 	return nil
@@ -132,6 +451,54 @@ func (m *Manager) Save(cfg *Config) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.save(cfg)
+}
+
+// Update applies change to a copy of the current configuration, then
+// validates and saves it, all under the manager lock so that concurrent
+// updates from the web UI and the bot cannot overwrite each other. change
+// returns false when nothing changed; Update then reports false and saves nothing.
+func (m *Manager) Update(change func(cfg *Config) bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.update(change)
+}
+
+// UpdateAt is Update for a change based on the configuration at revision,
+// as returned by GetRevision. It fails with ErrStale when the configuration
+// has been replaced since, so the change cannot undo edits it never saw.
+func (m *Manager) UpdateAt(revision uint64, change func(cfg *Config) bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.config != nil && revision != m.revision {
+		return false, ErrStale
+	}
+	return m.update(change)
+}
+
+// update implements Update; the caller holds the lock
+func (m *Manager) update(change func(cfg *Config) bool) (bool, error) {
+	if m.config == nil {
+		return false, fmt.Errorf("配置未加载")
+	}
+
+	cfg := m.config.clone()
+	if !change(cfg) {
+		return false, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if err := m.save(cfg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes cfg to file and makes it current; the caller holds the lock
+func (m *Manager) save(cfg *Config) error {
 	wrapper := ConfigWrapper{Config: cfg}
 	data, err := json.MarshalIndent(wrapper, "", "    ")
 	if err != nil {
@@ -143,6 +510,7 @@ func (m *Manager) Save(cfg *Config) error {
 	}
 
 	m.config = cfg
+	m.revision++
 	log.Info("配置文件保存成功")
 	return nil
 }
@@ -155,9 +523,52 @@ func (m *Manager) Get() *Config {
 	if m.config == nil {
 		return nil
 	}
+	return m.config.clone()
+}
+
+// GetRevision returns a copy of the current configuration with its
+// revision, for a later UpdateAt
+func (m *Manager) GetRevision() (*Config, uint64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.config == nil {
+		return nil, 0
+	}
+	return m.config.clone(), m.revision
+}
 
-	// Return a copy to prevent external modifications
-	configCopy := *m.config
+// clone returns a copy of cfg that shares no slices or maps with it, to
+// prevent external modifications
+func (cfg *Config) clone() *Config {
+	configCopy := *cfg
+	configCopy.URLs = append([]string(nil), cfg.URLs...)
+	configCopy.ExtraURLs = append([]string(nil), cfg.ExtraURLs...)
+	configCopy.CommentRoles = append([]string(nil), cfg.CommentRoles...)
+	configCopy.MutedThreads = append([]string(nil), cfg.MutedThreads...)
+	configCopy.ChatIDs = append([]string(nil), cfg.ChatIDs...)
+	configCopy.TelegramBotAdmins = append([]int64(nil), cfg.TelegramBotAdmins...)
+	configCopy.AIProviders = append([]string(nil), cfg.AIProviders...)
+	configCopy.Channels = append([]ChannelConfig(nil), cfg.Channels...)
+	configCopy.Webhook.Headers = copyHeaders(cfg.Webhook.Headers)
+	for i := range configCopy.Channels {
+		ch := &configCopy.Channels[i]
+		ch.ChatIDs = append([]string(nil), cfg.Channels[i].ChatIDs...)
+		ch.Webhook.Headers = copyHeaders(cfg.Channels[i].Webhook.Headers)
+		ch.Rules.Kinds = append([]string(nil), cfg.Channels[i].Rules.Kinds...)
+		ch.Rules.Domains = append([]string(nil), cfg.Channels[i].Rules.Domains...)
+		ch.Rules.Categories = append([]string(nil), cfg.Channels[i].Rules.Categories...)
+	}
+	configCopy.SourceFrequency = make(map[string]int, len(cfg.SourceFrequency))
+	for url, freq := range cfg.SourceFrequency {
+		configCopy.SourceFrequency[url] = freq
+	}
+	if cfg.AIPricing != nil {
+		configCopy.AIPricing = make(map[string]AIPrice, len(cfg.AIPricing))
+		for model, price := range cfg.AIPricing {
+			configCopy.AIPricing[model] = price
+		}
+	}
 	return &configCopy
 }
 
@@ -173,24 +584,98 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("频率必须至少为 10 秒")
 	}
 
+	for url, freq := range cfg.SourceFrequency {
+		if freq < 10 {
+			return fmt.Errorf("source_frequency 中 %s 的频率必须至少为 10 秒", url)
+		}
+	}
+
+	if cfg.MaxWorkers == 0 {
+		cfg.MaxWorkers = 4
+	}
+	if cfg.MaxWorkers < 1 || cfg.MaxWorkers > maxWorkers {
+		return fmt.Errorf("max_workers 必须在 1 到 %d 之间", maxWorkers)
+	}
+
+	// 0 is what a missing key loads as and becomes the default, so it is not a valid setting
+	if cfg.HostRateLimit < 1 {
+		return fmt.Errorf("host_rate_limit 必须至少为 1 毫秒")
+	}
+
 	if cfg.CommentFilter != "by_role" && cfg.CommentFilter != "by_author" {
 		return fmt.Errorf("comment_filter 必须是 'by_role' 或 'by_author'")
 	}
 
-	if cfg.NoticeType != "telegram" && cfg.NoticeType != "wechat" && cfg.NoticeType != "custom" {
-		return fmt.Errorf("notice_type 必须是 'telegram', 'wechat' 或 'custom'")
+	if cfg.CommentFilter == "by_role" && len(cfg.CommentRoles) == 0 {
+		cfg.CommentRoles = append([]string(nil), DefaultCommentRoles...)
+	}
+
+	if err := cfg.validateKeywordRules(); err != nil {
+		return err
+	}
+
+	if cfg.FilterMode == "" {
+		cfg.FilterMode = FilterModeAnd
+	}
+	if cfg.FilterMode != FilterModeAnd && cfg.FilterMode != FilterModeOr {
+		return fmt.Errorf("filter_mode 必须是 'and' 或 'or'")
+	}
+
+	if !isNoticeType(cfg.NoticeType) {
+		return fmt.Errorf("notice_type 必须是 'telegram'、'wechat'、'custom'、'discord'、'slack' 或 'teams'")
 	}
 
 	// Validate notification settings only if fields are provided
 	switch cfg.NoticeType {
 	case "telegram":
-		if (cfg.TelegramBot != "" || cfg.ChatID != "") && (cfg.TelegramBot == "" || cfg.ChatID == "") {
+		hasChat := cfg.ChatID != "" || len(cfg.ChatIDs) > 0
+		if (cfg.TelegramBot != "" || hasChat) && (cfg.TelegramBot == "" || !hasChat) {
 			return fmt.Errorf("Telegram 配置不完整: 需要同时填写 telegrambot 和 chat_id")
 		}
 	case "wechat":
 		// WeChat key is optional, no validation needed
 	case "custom":
-		// Custom URL is optional, no validation needed
+		// Custom URL is optional
+		if err := cfg.Webhook.Validate(); err != nil {
+			return err
+		}
+	case "discord", "slack", "teams":
+		// The webhook URL is optional, but must be valid when given
+		if webhookURL := cfg.ChatWebhook(cfg.NoticeType); webhookURL != "" {
+			if err := validateWebhookURL(cfg.NoticeType+"_webhook", webhookURL); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.TelegramParseMode == "" {
+		cfg.TelegramParseMode = TelegramParseHTML
+	}
+	if !isTelegramParseMode(cfg.TelegramParseMode) {
+		return fmt.Errorf("telegram_parse_mode 必须是 'html'、'markdownv2' 或 'plain'")
+	}
+	if _, err := TelegramTargets(cfg.ChatID, cfg.ChatIDs); err != nil {
+		return err
+	}
+	if cfg.TelegramRateLimit == 0 {
+		cfg.TelegramRateLimit = DefaultTelegramRateLimit
+	}
+	if cfg.TelegramRateLimit < 0 {
+		return fmt.Errorf("telegram_rate_limit 不能为负数")
+	}
+	if cfg.TelegramBotEnabled && (cfg.TelegramBot == "" || (cfg.ChatID == "" && len(cfg.ChatIDs) == 0)) {
+		return fmt.Errorf("启用 Telegram Bot 需要填写 telegrambot 和 chat_id")
+	}
+
+	// Validate additional notification channels
+	if err := cfg.nameChannels(); err != nil {
+		return err
+	}
+	for i := range cfg.Channels {
+		ch := &cfg.Channels[i]
+		if err := ch.Validate(); err != nil {
+			return fmt.Errorf("通知渠道 %s: %w", ch.Name, err)
+		}
 	}
 
 	// Validate AI settings if enabled
@@ -200,27 +685,66 @@ func (cfg *Config) Validate() error {
 			cfg.AIProvider = "cloudflare"
 		}
 
-		if cfg.AIProvider != "cloudflare" && cfg.AIProvider != "openai" {
-			return fmt.Errorf("ai_provider 必须是 'cloudflare' 或 'openai'")
+		if !isAIProvider(cfg.AIProvider) {
+			return fmt.Errorf("ai_provider 必须是 %s 之一", strings.Join(AIProviderNames, ", "))
 		}
 
-		switch cfg.AIProvider {
-		case "cloudflare":
-			if cfg.CFAccountID == "" || cfg.CFToken == "" {
-				return fmt.Errorf("Cloudflare AI 配置不完整: 需要 cf_account_id 和 cf_token")
-			}
-			if cfg.Model == "" {
-				return fmt.Errorf("Cloudflare AI 配置不完整: 需要 model")
+		if cfg.AIOutputMode == "" {
+			cfg.AIOutputMode = AIOutputText
+		}
+		if cfg.AIOutputMode != AIOutputText && cfg.AIOutputMode != AIOutputJSON {
+			return fmt.Errorf("ai_output_mode 必须是 'text' 或 'json'")
+		}
+
+		if cfg.AICacheTTL < 0 {
+			return fmt.Errorf("ai_cache_ttl 不能为负数")
+		}
+
+		if cfg.AIBreakerThreshold == 0 {
+			cfg.AIBreakerThreshold = 3
+		}
+		if cfg.AIBreakerCooldown == 0 {
+			cfg.AIBreakerCooldown = 300
+		}
+		if cfg.AIBreakerThreshold < 0 || cfg.AIBreakerCooldown < 0 {
+			return fmt.Errorf("ai_breaker_threshold 和 ai_breaker_cooldown 不能为负数")
+		}
+
+		if cfg.AIFailurePolicy == "" {
+			cfg.AIFailurePolicy = AIFailureNotify
+		}
+		if cfg.AIFailurePolicy != AIFailureNotify && cfg.AIFailurePolicy != AIFailureHold && cfg.AIFailurePolicy != AIFailureDrop {
+			return fmt.Errorf("ai_failure_policy 必须是 'notify'、'hold' 或 'drop'")
+		}
+
+		if _, err := prompt.Parse(cfg.ThreadPrompt); err != nil {
+			return fmt.Errorf("thread_prompt 无效: %w", err)
+		}
+		if _, err := prompt.Parse(cfg.CommentPrompt); err != nil {
+			return fmt.Errorf("comment_prompt 无效: %w", err)
+		}
+
+		if cfg.AIDailyTokenBudget < 0 || cfg.AIDailyCostBudget < 0 {
+			return fmt.Errorf("ai_daily_token_budget 和 ai_daily_cost_budget 不能为负数")
+		}
+		for model, price := range cfg.AIPricing {
+			if price.Input < 0 || price.Output < 0 {
+				return fmt.Errorf("ai_pricing 中模型 %s 的价格不能为负数", model)
 			}
-		case "openai":
-			if cfg.OpenAIAPIURL == "" {
-				return fmt.Errorf("OpenAI 配置不完整: 需要 openai_api_url")
+		}
+
+		seen := make(map[string]bool)
+		for _, name := range cfg.AIProviderChain() {
+			if !isAIProvider(name) {
+				return fmt.Errorf("ai_providers 包含不支持的 AI 提供商: %s", name)
 			}
-			if cfg.OpenAIAPIKey == "" {
-				return fmt.Errorf("OpenAI 配置不完整: 需要 openai_api_key")
+			if seen[name] {
+				return fmt.Errorf("ai_providers 中的 AI 提供商重复: %s", name)
 			}
-			if cfg.OpenAIModel == "" {
-				return fmt.Errorf("OpenAI 配置不完整: 需要 openai_model")
+			seen[name] = true
+
+			if err := cfg.validateAIProvider(name); err != nil {
+				return err
 			}
 		}
 	}
@@ -228,6 +752,378 @@ func (cfg *Config) Validate() error {
 	return nil
 }
 
+// validateAIProvider checks that the settings of an AI provider are complete
+func (cfg *Config) validateAIProvider(name string) error {
+	switch name {
+	case "cloudflare":
+		if cfg.CFAccountID == "" || cfg.CFToken == "" {
+			return fmt.Errorf("Cloudflare AI 配置不完整: 需要 cf_account_id 和 cf_token")
+		}
+		if cfg.Model == "" {
+			return fmt.Errorf("Cloudflare AI 配置不完整: 需要 model")
+		}
+	case "openai":
+		if cfg.OpenAIAPIURL == "" {
+			return fmt.Errorf("OpenAI 配置不完整: 需要 openai_api_url")
+		}
+		if cfg.OpenAIAPIKey == "" {
+			return fmt.Errorf("OpenAI 配置不完整: 需要 openai_api_key")
+		}
+		if cfg.OpenAIModel == "" {
+			return fmt.Errorf("OpenAI 配置不完整: 需要 openai_model")
+		}
+	case "anthropic":
+		if cfg.AnthropicAPIURL == "" {
+			cfg.AnthropicAPIURL = DefaultAnthropicAPIURL
+		}
+		if cfg.AnthropicMaxTokens == 0 {
+			cfg.AnthropicMaxTokens = DefaultAnthropicMaxTokens
+		}
+		if cfg.AnthropicAPIKey == "" {
+			return fmt.Errorf("Anthropic 配置不完整: 需要 anthropic_api_key")
+		}
+		if cfg.AnthropicModel == "" {
+			return fmt.Errorf("Anthropic 配置不完整: 需要 anthropic_model")
+		}
+		if cfg.AnthropicMaxTokens < 0 {
+			return fmt.Errorf("anthropic_max_tokens 不能为负数")
+		}
+	case "ollama":
+		if cfg.OllamaURL == "" {
+			cfg.OllamaURL = DefaultOllamaURL
+		}
+		if cfg.OllamaModel == "" {
+			return fmt.Errorf("Ollama 配置不完整: 需要 ollama_model")
+		}
+	}
+	return nil
+}
+
+// validateKeywordRules compiles the keyword rules, so that an invalid rule is
+// rejected instead of disabling keyword filtering
+func (cfg *Config) validateKeywordRules() error {
+	for name, rule := range map[string]string{
+		"keywords_rule":         cfg.KeywordsRule,
+		"thread_keywords_rule":  cfg.ThreadKeywordsRule,
+		"comment_keywords_rule": cfg.CommentKeywordsRule,
+	} {
+		if _, err := expr.Compile(rule); err != nil {
+			return fmt.Errorf("%s 无效: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// nameChannels names the unnamed notification channels after their type
+// and position and rejects duplicate names, since outbox retries find their
+// channel by name
+func (cfg *Config) nameChannels() error {
+	names := make(map[string]bool)
+	for i := range cfg.Channels {
+		ch := &cfg.Channels[i]
+		if ch.Name == "" {
+			ch.Name = fmt.Sprintf("%s-%d", ch.Type, i+1)
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("通知渠道名称重复: %s", ch.Name)
+		}
+		names[ch.Name] = true
+	}
+	return nil
+}
+
+// isAIProvider reports whether name is a supported AI provider
+func isAIProvider(name string) bool {
+	for _, p := range AIProviderNames {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates a notification channel definition
+func (ch *ChannelConfig) Validate() error {
+	switch ch.Type {
+	case "telegram":
+		if ch.TelegramBot == "" || (ch.ChatID == "" && len(ch.ChatIDs) == 0) {
+			return fmt.Errorf("Telegram 配置不完整: 需要同时填写 telegrambot 和 chat_id")
+		}
+		if _, err := ch.TelegramTargets(); err != nil {
+			return err
+		}
+	case "wechat":
+		if ch.WeChatKey == "" {
+			return fmt.Errorf("微信配置不完整: 需要 wechat_key")
+		}
+	case "custom":
+		if ch.CustomURL == "" {
+			return fmt.Errorf("自定义通知配置不完整: 需要 custom_url")
+		}
+		if err := ch.Webhook.Validate(); err != nil {
+			return err
+		}
+	case "discord", "slack", "teams":
+		if err := validateWebhookURL(ch.Type+"_webhook", ch.ChatWebhook()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("type 必须是 'telegram'、'wechat'、'custom'、'discord'、'slack' 或 'teams'")
+	}
+
+	for _, kind := range ch.Rules.Kinds {
+		if kind != "thread" && kind != "comment" {
+			return fmt.Errorf("rules.kinds 只能包含 'thread' 或 'comment'")
+		}
+	}
+
+	if _, err := expr.Compile(ch.Rules.Keywords); err != nil {
+		return fmt.Errorf("rules.keywords 无效: %w", err)
+	}
+
+	if ch.ParseMode != "" && !isTelegramParseMode(ch.ParseMode) {
+		return fmt.Errorf("parse_mode 必须是 'html'、'markdownv2' 或 'plain'")
+	}
+	if ch.RateLimit < 0 {
+		return fmt.Errorf("rate_limit 不能为负数")
+	}
+
+	return nil
+}
+
+// TelegramTarget is a telegram chat, or a forum topic of a chat, notifications are sent to
+type TelegramTarget struct {
+	ChatID          string
+	MessageThreadID int // forum topic, 0 for the chat itself
+}
+
+// String formats the target like it is configured
+func (t TelegramTarget) String() string {
+	if t.MessageThreadID == 0 {
+		return t.ChatID
+	}
+	return fmt.Sprintf("%s:%d", t.ChatID, t.MessageThreadID)
+}
+
+// ParseTelegramTarget parses "chat_id" or "chat_id:message_thread_id",
+// e.g. "-1001234567890:42" for topic 42 of a forum supergroup
+func ParseTelegramTarget(s string) (TelegramTarget, error) {
+	target := TelegramTarget{ChatID: strings.TrimSpace(s)}
+	if i := strings.LastIndex(target.ChatID, ":"); i >= 0 {
+		topic, err := strconv.Atoi(target.ChatID[i+1:])
+		if err != nil || topic <= 0 {
+			return TelegramTarget{}, fmt.Errorf("无效的 Telegram 话题: %s", s)
+		}
+		target = TelegramTarget{ChatID: target.ChatID[:i], MessageThreadID: topic}
+	}
+	if target.ChatID == "" {
+		return TelegramTarget{}, fmt.Errorf("无效的 Telegram chat_id: %s", s)
+	}
+	return target, nil
+}
+
+// TelegramTargets parses chat_id followed by chat_ids, skipping empty and
+// duplicate entries
+func TelegramTargets(chatID string, chatIDs []string) ([]TelegramTarget, error) {
+	var targets []TelegramTarget
+	seen := make(map[TelegramTarget]bool)
+	for _, s := range append([]string{chatID}, chatIDs...) {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		target, err := ParseTelegramTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// TelegramTargets returns the chats a telegram channel sends to
+func (ch *ChannelConfig) TelegramTargets() ([]TelegramTarget, error) {
+	return TelegramTargets(ch.ChatID, ch.ChatIDs)
+}
+
+// ChatWebhook returns the incoming webhook URL of a discord, slack or teams channel
+func (ch *ChannelConfig) ChatWebhook() string {
+	switch ch.Type {
+	case "discord":
+		return ch.DiscordWebhook
+	case "slack":
+		return ch.SlackWebhook
+	case "teams":
+		return ch.TeamsWebhook
+	default:
+		return ""
+	}
+}
+
+// ChatWebhook returns the configured incoming webhook URL of a notice type
+func (cfg *Config) ChatWebhook(noticeType string) string {
+	switch noticeType {
+	case "discord":
+		return cfg.DiscordWebhook
+	case "slack":
+		return cfg.SlackWebhook
+	case "teams":
+		return cfg.TeamsWebhook
+	default:
+		return ""
+	}
+}
+
+// validateWebhookURL checks that an incoming webhook URL is an absolute http(s) URL
+func validateWebhookURL(name, webhookURL string) error {
+	if webhookURL == "" {
+		return fmt.Errorf("通知配置不完整: 需要 %s", name)
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s 必须是 http(s) 地址", name)
+	}
+	return nil
+}
+
+// isNoticeType reports whether t is a supported notification type
+func isNoticeType(t string) bool {
+	for _, nt := range NoticeTypes {
+		if nt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// isTelegramParseMode reports whether mode is a supported Telegram parse mode
+func isTelegramParseMode(mode string) bool {
+	return mode == TelegramParseHTML || mode == TelegramParseMarkdownV2 || mode == TelegramParsePlain
+}
+
+// NotificationChannels returns the enabled notification channels. When no
+// channels are configured, the legacy notice_type settings form a single channel.
+func (cfg *Config) NotificationChannels() []ChannelConfig {
+	if len(cfg.Channels) == 0 {
+		return []ChannelConfig{cfg.withTelegramDefaults(ChannelConfig{
+			Name:           cfg.NoticeType,
+			Type:           cfg.NoticeType,
+			TelegramBot:    cfg.TelegramBot,
+			ChatID:         cfg.ChatID,
+			ChatIDs:        cfg.ChatIDs,
+			WeChatKey:      cfg.WeChatKey,
+			CustomURL:      cfg.CustomURL,
+			Webhook:        cfg.Webhook,
+			DiscordWebhook: cfg.DiscordWebhook,
+			SlackWebhook:   cfg.SlackWebhook,
+			TeamsWebhook:   cfg.TeamsWebhook,
+			DisablePreview: cfg.TelegramDisablePreview,
+		})}
+	}
+
+	var channels []ChannelConfig
+	for _, ch := range cfg.Channels {
+		if !ch.Disabled {
+			channels = append(channels, cfg.withTelegramDefaults(ch))
+		}
+	}
+	return channels
+}
+
+// withTelegramDefaults fills the telegram settings a channel inherits
+func (cfg *Config) withTelegramDefaults(ch ChannelConfig) ChannelConfig {
+	if ch.Type != "telegram" {
+		return ch
+	}
+	if ch.ParseMode == "" {
+		ch.ParseMode = cfg.TelegramParseMode
+	}
+	if ch.RateLimit == 0 {
+		ch.RateLimit = cfg.TelegramRateLimit
+	}
+	ch.MuteButton = cfg.TelegramBotEnabled && ch.TelegramBot == cfg.TelegramBot
+	return ch
+}
+
+// IsThreadMuted reports whether new comments of a thread are muted
+func (cfg *Config) IsThreadMuted(link string) bool {
+	for _, muted := range cfg.MutedThreads {
+		if muted == link {
+			return true
+		}
+	}
+	return false
+}
+
+// AIProviderChain returns the AI providers in the order they are tried
+func (cfg *Config) AIProviderChain() []string {
+	if len(cfg.AIProviders) > 0 {
+		return cfg.AIProviders
+	}
+	return []string{cfg.AIProvider}
+}
+
+// AIModel returns the model configured for an AI provider
+func (cfg *Config) AIModel(provider string) string {
+	switch provider {
+	case "openai":
+		return cfg.OpenAIModel
+	case "anthropic":
+		return cfg.AnthropicModel
+	case "ollama":
+		return cfg.OllamaModel
+	default:
+		return cfg.Model
+	}
+}
+
+// SourceInterval returns the check interval for a source URL
+func (cfg *Config) SourceInterval(url string) time.Duration {
+	if freq, ok := cfg.SourceFrequency[url]; ok && freq > 0 {
+		return time.Duration(freq) * time.Second
+	}
+	return time.Duration(cfg.Frequency) * time.Second
+}
+
+// CommentKeywordRule returns the keyword rule applied to comments
+func (cfg *Config) CommentKeywordRule() string {
+	if cfg.CommentKeywordsRule != "" {
+		return cfg.CommentKeywordsRule
+	}
+	return cfg.KeywordsRule
+}
+
+// HasCommentRole reports whether a comment role matches one of the configured roles.
+// A role title may list several roles separated by commas, e.g. "Top Host, Provider".
+func (cfg *Config) HasCommentRole(role string) bool {
+	for _, r := range strings.Split(role, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		for _, allowed := range cfg.CommentRoles {
+			if strings.EqualFold(r, strings.TrimSpace(allowed)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyHeaders copies webhook headers; nil stays nil
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	c := make(map[string]string, len(headers))
+	for name, value := range headers {
+		c[name] = value
+	}
+	return c
+}
+
 // copyFile copies a file from src to dst
 func copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
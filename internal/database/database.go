@@ -18,11 +18,34 @@ type Database interface {
 	InsertThread(thread *Thread) error
 	FindThread(link string) (*Thread, error)
 	UpdateThreadLastPage(link string, page int) error
+	UpdateThreadStatus(link, status, aiSummary string, decision *Decision) error
 
 	// Comment operations
 	InsertComment(comment *Comment) error
 	FindComment(commentID string) (*Comment, error)
 	CommentExists(commentID string) bool
+	UpdateCommentStatus(commentID, status, aiSummary string, decision *Decision) error
+
+	// Browsing operations
+	FindThreadByID(id string) (*Thread, error)
+	ListThreads(query ThreadQuery) ([]*Thread, int64, error)
+	ListComments(query CommentQuery) ([]*Comment, int64, error)
+
+	// Notification outbox operations
+	InsertOutbox(entry *OutboxEntry) error
+	UpdateOutbox(entry *OutboxEntry) error
+	ListDueOutbox(now time.Time, limit int) ([]*OutboxEntry, error)
+	ListOutbox(status string, limit int) ([]*OutboxEntry, error)
+	RequeueOutbox(id string) error
+
+	// AI result cache operations
+	FindAICache(key string) (*AICacheEntry, error)
+	UpsertAICache(entry *AICacheEntry) error
+	DeleteExpiredAICache(now time.Time) (int64, error)
+
+	// AI usage accounting
+	InsertAIUsage(usage *AIUsage) error
+	SummarizeAIUsage(since, until time.Time) ([]*AIUsageSummary, error)
 
 	// Connection management
 	Disconnect() error
@@ -31,26 +54,195 @@ type Database interface {
 
 // Thread represents a forum thread/post
 type Thread struct {
-	ID          interface{} `json:"id"` // string for SQLite, ObjectID for MongoDB
-	Domain      string      `json:"domain"`
-	Category    string      `json:"category"`
-	Title       string      `json:"title"`
-	Link        string      `json:"link"`
-	Description string      `json:"description"`
-	Creator     string      `json:"creator"`
-	PubDate     time.Time   `json:"pub_date"`
-	CreatedAt   time.Time   `json:"created_at"`
-	LastPage    int         `json:"last_page"`
+	ID          interface{} `json:"id" bson:"_id,omitempty"` // int64 for SQLite, ObjectID for MongoDB
+	Domain      string      `json:"domain" bson:"domain"`
+	Category    string      `json:"category" bson:"category"`
+	Title       string      `json:"title" bson:"title"`
+	Link        string      `json:"link" bson:"link"`
+	Description string      `json:"description" bson:"description"`
+	Creator     string      `json:"creator" bson:"creator"`
+	PubDate     time.Time   `json:"pub_date" bson:"pub_date"`
+	CreatedAt   time.Time   `json:"created_at" bson:"created_at"`
+	LastPage    int         `json:"last_page" bson:"last_page"`
+	Status      string      `json:"status" bson:"status"`         // processing outcome, see Status* constants
+	AISummary   string      `json:"ai_summary" bson:"ai_summary"` // AI filter output, if any
+	Decision    *Decision   `json:"decision,omitempty" bson:"decision,omitempty"`
+	Offer       *Offer      `json:"offer,omitempty" bson:"offer,omitempty"` // prices and specs extracted from the post, nil when none found
 }
 
 // Comment represents a comment on a thread
 type Comment struct {
-	ID                interface{} `json:"id"` // string for SQLite, ObjectID for MongoDB
-	CommentID         string      `json:"comment_id"`
-	ThreadURL         string      `json:"thread_url"`
-	Author            string      `json:"author"`
-	Message           string      `json:"message"`
-	CreatedAt         time.Time   `json:"created_at"`
-	CreatedAtRecorded time.Time   `json:"created_at_recorded"`
-	URL               string      `json:"url"`
+	ID                interface{} `json:"id" bson:"_id,omitempty"` // int64 for SQLite, ObjectID for MongoDB
+	CommentID         string      `json:"comment_id" bson:"comment_id"`
+	ThreadURL         string      `json:"thread_url" bson:"thread_url"`
+	Author            string      `json:"author" bson:"author"`
+	Role              string      `json:"role" bson:"role"` // forum role title, e.g. "Provider", "Host Rep"
+	Message           string      `json:"message" bson:"message"`
+	CreatedAt         time.Time   `json:"created_at" bson:"created_at"`
+	CreatedAtRecorded time.Time   `json:"created_at_recorded" bson:"created_at_recorded"`
+	URL               string      `json:"url" bson:"url"`
+	Status            string      `json:"status" bson:"status"`         // processing outcome, see Status* constants
+	AISummary         string      `json:"ai_summary" bson:"ai_summary"` // AI filter output, if any
+	Decision          *Decision   `json:"decision,omitempty" bson:"decision,omitempty"`
+}
+
+// Processing outcomes of a thread or comment. Records stored before outcomes
+// were tracked have an empty status.
+const (
+	StatusPending         = "pending"          // stored, filters not finished yet
+	StatusTooOld          = "too_old"          // older than 24 hours, not notified
+	StatusMuted           = "muted"            // comment on a muted thread, not notified
+	StatusKeywordFiltered = "keyword_filtered" // rejected by the keyword filter
+	StatusAIRejected      = "ai_rejected"      // rejected by the AI filter
+	StatusAIHeld          = "ai_held"          // no AI provider answered, waiting for re-evaluation
+	StatusAIFailed        = "ai_failed"        // no AI provider answered, dropped
+	StatusUnrouted        = "unrouted"         // no notification channel accepted it
+	StatusNotified        = "notified"         // delivered to at least one channel
+	StatusQueued          = "queued"           // every delivery failed, waiting in the outbox
+)
+
+// Decision records which filters ran on a thread or comment, what they
+// returned and how the notification was delivered
+type Decision struct {
+	Filters     []string         `json:"filters" bson:"filters"`                               // filters applied in order, e.g. "comment_filter:by_role", "keyword", "ai"
+	KeywordRule string           `json:"keyword_rule,omitempty" bson:"keyword_rule,omitempty"` // keyword rule group that matched
+	AIResult    string           `json:"ai_result,omitempty" bson:"ai_result,omitempty"`       // AI filter output as returned by Filter
+	AIError     string           `json:"ai_error,omitempty" bson:"ai_error,omitempty"`         // AI call failure, handled according to ai_failure_policy
+	AISkipped   string           `json:"ai_skipped,omitempty" bson:"ai_skipped,omitempty"`     // why the enabled AI filter did not run, see AISkipped* constants
+	AIAccepted  *bool            `json:"ai_accepted,omitempty" bson:"ai_accepted,omitempty"`   // IsValidResult outcome, nil when the AI filter did not decide
+	AIVerdict   *AIVerdict       `json:"ai_verdict,omitempty" bson:"ai_verdict,omitempty"`     // parsed AI result in JSON output mode
+	Deliveries  []DeliveryRecord `json:"deliveries,omitempty" bson:"deliveries,omitempty"`
+	DecidedAt   time.Time        `json:"decided_at" bson:"decided_at"`
+}
+
+// Reasons for skipping the AI filter
+const (
+	AISkippedBudget = "budget" // the daily token or cost budget is used up
+)
+
+// AIVerdict is the structured answer of the AI filter in JSON output mode
+type AIVerdict struct {
+	Relevant     bool    `json:"relevant" bson:"relevant"`
+	Summary      string  `json:"summary" bson:"summary"` // summary of a thread or translation of a comment
+	CheapestPlan string  `json:"cheapest_plan,omitempty" bson:"cheapest_plan,omitempty"`
+	Coupon       string  `json:"coupon,omitempty" bson:"coupon,omitempty"`
+	Confidence   float64 `json:"confidence" bson:"confidence"` // 0 to 1
+}
+
+// DeliveryRecord is the delivery outcome on one notification channel
+type DeliveryRecord struct {
+	Channel string    `json:"channel" bson:"channel"`
+	Status  string    `json:"status" bson:"status"` // see Delivery* constants
+	Error   string    `json:"error,omitempty" bson:"error,omitempty"`
+	At      time.Time `json:"at" bson:"at"`
+}
+
+// Delivery record statuses
+const (
+	DeliverySent   = "sent"   // delivered
+	DeliveryQueued = "queued" // failed, retried through the outbox
+	DeliveryDead   = "dead"   // outbox gave up
+)
+
+// Offer is the structured pricing and hardware data extracted from a post
+type Offer struct {
+	Plans     []OfferPlan `json:"plans" bson:"plans"`
+	Locations []string    `json:"locations,omitempty" bson:"locations,omitempty"`
+}
+
+// OfferPlan is one priced plan of an offer. Zero values mean the spec was not found.
+type OfferPlan struct {
+	Price        float64 `json:"price" bson:"price"`                                     // as written in the post
+	Currency     string  `json:"currency" bson:"currency"`                               // ISO code, e.g. "USD"
+	PeriodMonths int     `json:"period_months,omitempty" bson:"period_months,omitempty"` // billing period, 0 when unknown or one-time
+	OneTime      bool    `json:"one_time,omitempty" bson:"one_time,omitempty"`
+	RAMGB        float64 `json:"ram_gb,omitempty" bson:"ram_gb,omitempty"`
+	DiskGB       float64 `json:"disk_gb,omitempty" bson:"disk_gb,omitempty"`
+	BandwidthGB  float64 `json:"bandwidth_gb,omitempty" bson:"bandwidth_gb,omitempty"` // monthly transfer
+	Unmetered    bool    `json:"unmetered,omitempty" bson:"unmetered,omitempty"`
+	CPUCores     int     `json:"cpu_cores,omitempty" bson:"cpu_cores,omitempty"`
+}
+
+// ThreadQuery filters and paginates thread listings. Empty fields match everything.
+type ThreadQuery struct {
+	Domain   string    // substring of the thread domain
+	Category string    // exact category, case-insensitive
+	Creator  string    // exact creator, case-insensitive
+	Status   string    // exact processing status
+	Search   string    // substring of title or description
+	Since    time.Time // pub_date >= Since
+	Until    time.Time // pub_date < Until
+	Oldest   bool      // oldest first instead of newest first
+	Offset   int
+	Limit    int
+}
+
+// CommentQuery filters and paginates comment listings. Empty fields match everything.
+type CommentQuery struct {
+	ThreadURL string    // comments of a single thread
+	Domain    string    // substring of the thread URL
+	Author    string    // exact author, case-insensitive
+	Role      string    // substring of the role title
+	Status    string    // exact processing status
+	Search    string    // substring of the message
+	Since     time.Time // created_at >= Since
+	Until     time.Time // created_at < Until
+	Oldest    bool      // oldest first instead of newest first
+	Offset    int
+	Limit     int
+}
+
+// Outbox entry statuses
+const (
+	OutboxPending = "pending" // waiting for the next delivery attempt
+	OutboxSent    = "sent"    // delivered after one or more retries
+	OutboxDead    = "dead"    // gave up after too many attempts
+)
+
+// OutboxEntry is a notification that failed to deliver and is waiting for retry
+type OutboxEntry struct {
+	ID            interface{} `json:"id" bson:"_id,omitempty"` // int64 for SQLite, ObjectID for MongoDB
+	Channel       string      `json:"channel" bson:"channel"`
+	Kind          string      `json:"kind" bson:"kind"` // "thread" or "comment"
+	ThreadLink    string      `json:"thread_link" bson:"thread_link"`
+	CommentID     string      `json:"comment_id" bson:"comment_id"`
+	AIDescription string      `json:"ai_description" bson:"ai_description"`
+	Targets       []string    `json:"targets,omitempty" bson:"targets,omitempty"` // targets of the channel still to send to, all when empty
+	Status        string      `json:"status" bson:"status"`
+	Attempts      int         `json:"attempts" bson:"attempts"`
+	LastError     string      `json:"last_error" bson:"last_error"`
+	NextAttemptAt time.Time   `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// AICacheEntry is a cached AI filter result
+type AICacheEntry struct {
+	Key       string    `json:"key" bson:"key"` // hash of provider, model, output mode, prompt and content
+	Provider  string    `json:"provider" bson:"provider"`
+	Model     string    `json:"model" bson:"model"`
+	Result    string    `json:"result" bson:"result"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// AIUsage is the token usage and cost of one AI request
+type AIUsage struct {
+	Provider         string    `json:"provider" bson:"provider"`
+	Model            string    `json:"model" bson:"model"`
+	PromptTokens     int       `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens" bson:"completion_tokens"`
+	Cost             float64   `json:"cost" bson:"cost"` // USD, 0 when the model has no price
+	CreatedAt        time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AIUsageSummary aggregates the AI usage of one provider and model on one UTC day
+type AIUsageSummary struct {
+	Date             string  `json:"date" bson:"date"` // YYYY-MM-DD
+	Provider         string  `json:"provider" bson:"provider"`
+	Model            string  `json:"model" bson:"model"`
+	Calls            int64   `json:"calls" bson:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens" bson:"completion_tokens"`
+	Cost             float64 `json:"cost" bson:"cost"`
 }
@@ -23,14 +23,25 @@ const (
 	TypeSQLite  DatabaseType = "sqlite"
 )
 
-// NewDatabase creates a new database instance based on the provided type and connection string
+// NewDatabase creates a new database instance based on the provided type and connection string.
+// The returned database records operation latency metrics.
 func NewDatabase(dbType DatabaseType, connectionString string) (Database, error) {
+	var (
+		db  Database
+		err error
+	)
+
 	switch strings.ToLower(string(dbType)) {
 	case string(TypeMongoDB):
-		return NewMongoDB(connectionString)
+		db, err = NewMongoDB(connectionString)
 	case string(TypeSQLite):
-		return NewSQLite(connectionString)
+		db, err = NewSQLite(connectionString)
 	default:
 		return nil, fmt.Errorf("不支持的数据库类型: %s", dbType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewInstrumentedDatabase(db), nil
 }
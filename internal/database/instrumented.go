@@ -0,0 +1,211 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Instrumented Database Decorator"
+//   Timestamp: "2025-12-08T11:25:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "DB latency needed for both backends without touching each implementation"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Decorator Pattern"
+//   Quality_Check: "Every Database method recorded with operation and result labels"
+// }}
+
+package database
+
+import (
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+)
+
+// InstrumentedDatabase wraps a Database and records operation latency metrics
+type InstrumentedDatabase struct {
+	db Database
+}
+
+// Ensure InstrumentedDatabase implements Database interface
+var _ Database = (*InstrumentedDatabase)(nil)
+
+// NewInstrumentedDatabase wraps db with metrics instrumentation
+func NewInstrumentedDatabase(db Database) *InstrumentedDatabase {
+	return &InstrumentedDatabase{db: db}
+}
+
+// InsertThread inserts a new thread
+func (d *InstrumentedDatabase) InsertThread(thread *Thread) error {
+	start := time.Now()
+	err := d.db.InsertThread(thread)
+	metrics.ObserveDB("insert_thread", err, start)
+	return err
+}
+
+// FindThread finds a thread by link
+func (d *InstrumentedDatabase) FindThread(link string) (*Thread, error) {
+	start := time.Now()
+	thread, err := d.db.FindThread(link)
+	metrics.ObserveDB("find_thread", err, start)
+	return thread, err
+}
+
+// UpdateThreadLastPage updates the last_page field
+func (d *InstrumentedDatabase) UpdateThreadLastPage(link string, page int) error {
+	start := time.Now()
+	err := d.db.UpdateThreadLastPage(link, page)
+	metrics.ObserveDB("update_thread_last_page", err, start)
+	return err
+}
+
+// UpdateThreadStatus records the processing outcome and decision of a thread
+func (d *InstrumentedDatabase) UpdateThreadStatus(link, status, aiSummary string, decision *Decision) error {
+	start := time.Now()
+	err := d.db.UpdateThreadStatus(link, status, aiSummary, decision)
+	metrics.ObserveDB("update_thread_status", err, start)
+	return err
+}
+
+// InsertComment inserts a new comment
+func (d *InstrumentedDatabase) InsertComment(comment *Comment) error {
+	start := time.Now()
+	err := d.db.InsertComment(comment)
+	metrics.ObserveDB("insert_comment", err, start)
+	return err
+}
+
+// FindComment finds a comment by comment_id
+func (d *InstrumentedDatabase) FindComment(commentID string) (*Comment, error) {
+	start := time.Now()
+	comment, err := d.db.FindComment(commentID)
+	metrics.ObserveDB("find_comment", err, start)
+	return comment, err
+}
+
+// UpdateCommentStatus records the processing outcome and decision of a comment
+func (d *InstrumentedDatabase) UpdateCommentStatus(commentID, status, aiSummary string, decision *Decision) error {
+	start := time.Now()
+	err := d.db.UpdateCommentStatus(commentID, status, aiSummary, decision)
+	metrics.ObserveDB("update_comment_status", err, start)
+	return err
+}
+
+// CommentExists checks if a comment exists
+func (d *InstrumentedDatabase) CommentExists(commentID string) bool {
+	start := time.Now()
+	exists := d.db.CommentExists(commentID)
+	metrics.ObserveDB("comment_exists", nil, start)
+	return exists
+}
+
+// FindThreadByID finds a thread by its database ID
+func (d *InstrumentedDatabase) FindThreadByID(id string) (*Thread, error) {
+	start := time.Now()
+	thread, err := d.db.FindThreadByID(id)
+	metrics.ObserveDB("find_thread_by_id", err, start)
+	return thread, err
+}
+
+// ListThreads returns threads matching the query
+func (d *InstrumentedDatabase) ListThreads(query ThreadQuery) ([]*Thread, int64, error) {
+	start := time.Now()
+	threads, total, err := d.db.ListThreads(query)
+	metrics.ObserveDB("list_threads", err, start)
+	return threads, total, err
+}
+
+// ListComments returns comments matching the query
+func (d *InstrumentedDatabase) ListComments(query CommentQuery) ([]*Comment, int64, error) {
+	start := time.Now()
+	comments, total, err := d.db.ListComments(query)
+	metrics.ObserveDB("list_comments", err, start)
+	return comments, total, err
+}
+
+// InsertOutbox inserts a new outbox entry
+func (d *InstrumentedDatabase) InsertOutbox(entry *OutboxEntry) error {
+	start := time.Now()
+	err := d.db.InsertOutbox(entry)
+	metrics.ObserveDB("insert_outbox", err, start)
+	return err
+}
+
+// UpdateOutbox updates the delivery state of an outbox entry
+func (d *InstrumentedDatabase) UpdateOutbox(entry *OutboxEntry) error {
+	start := time.Now()
+	err := d.db.UpdateOutbox(entry)
+	metrics.ObserveDB("update_outbox", err, start)
+	return err
+}
+
+// ListDueOutbox returns pending outbox entries whose next attempt is due
+func (d *InstrumentedDatabase) ListDueOutbox(now time.Time, limit int) ([]*OutboxEntry, error) {
+	start := time.Now()
+	entries, err := d.db.ListDueOutbox(now, limit)
+	metrics.ObserveDB("list_due_outbox", err, start)
+	return entries, err
+}
+
+// ListOutbox returns outbox entries, optionally filtered by status
+func (d *InstrumentedDatabase) ListOutbox(status string, limit int) ([]*OutboxEntry, error) {
+	start := time.Now()
+	entries, err := d.db.ListOutbox(status, limit)
+	metrics.ObserveDB("list_outbox", err, start)
+	return entries, err
+}
+
+// RequeueOutbox moves an outbox entry back to pending
+func (d *InstrumentedDatabase) RequeueOutbox(id string) error {
+	start := time.Now()
+	err := d.db.RequeueOutbox(id)
+	metrics.ObserveDB("requeue_outbox", err, start)
+	return err
+}
+
+// FindAICache looks up a cached AI result
+func (d *InstrumentedDatabase) FindAICache(key string) (*AICacheEntry, error) {
+	start := time.Now()
+	entry, err := d.db.FindAICache(key)
+	metrics.ObserveDB("find_ai_cache", err, start)
+	return entry, err
+}
+
+// UpsertAICache stores an AI result
+func (d *InstrumentedDatabase) UpsertAICache(entry *AICacheEntry) error {
+	start := time.Now()
+	err := d.db.UpsertAICache(entry)
+	metrics.ObserveDB("upsert_ai_cache", err, start)
+	return err
+}
+
+// DeleteExpiredAICache removes expired cache entries
+func (d *InstrumentedDatabase) DeleteExpiredAICache(now time.Time) (int64, error) {
+	start := time.Now()
+	n, err := d.db.DeleteExpiredAICache(now)
+	metrics.ObserveDB("delete_expired_ai_cache", err, start)
+	return n, err
+}
+
+// InsertAIUsage records the usage of an AI request
+func (d *InstrumentedDatabase) InsertAIUsage(usage *AIUsage) error {
+	start := time.Now()
+	err := d.db.InsertAIUsage(usage)
+	metrics.ObserveDB("insert_ai_usage", err, start)
+	return err
+}
+
+// SummarizeAIUsage aggregates AI usage per day, provider and model
+func (d *InstrumentedDatabase) SummarizeAIUsage(since, until time.Time) ([]*AIUsageSummary, error) {
+	start := time.Now()
+	summaries, err := d.db.SummarizeAIUsage(since, until)
+	metrics.ObserveDB("summarize_ai_usage", err, start)
+	return summaries, err
+}
+
+// Disconnect closes the underlying connection
+func (d *InstrumentedDatabase) Disconnect() error {
+	return d.db.Disconnect()
+}
+
+// Ping checks if the connection is alive
+func (d *InstrumentedDatabase) Ping() error {
+	start := time.Now()
+	err := d.db.Ping()
+	metrics.ObserveDB("ping", err, start)
+	return err
+}
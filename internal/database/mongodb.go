@@ -13,10 +13,12 @@ package database
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -27,8 +29,14 @@ type MongoDB struct {
 	db       *mongo.Database
 	threads  *mongo.Collection
 	comments *mongo.Collection
+	outbox   *mongo.Collection
+	aiCache  *mongo.Collection
+	aiUsage  *mongo.Collection
 }
 
+// Ensure MongoDB implements Database interface
+var _ Database = (*MongoDB)(nil)
+
 // NewMongoDB creates a new MongoDB connection
 func NewMongoDB(uri string) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -50,6 +58,9 @@ func NewMongoDB(uri string) (*MongoDB, error) {
 		db:       db,
 		threads:  db.Collection("threads"),
 		comments: db.Collection("comments"),
+		outbox:   db.Collection("notification_outbox"),
+		aiCache:  db.Collection("ai_cache"),
+		aiUsage:  db.Collection("ai_usage"),
 	}
 
 	if err := m.createIndexes(); err != nil {
@@ -98,6 +109,41 @@ func (m *MongoDB) createIndexes() error {
 		return fmt.Errorf("创建 comments 索引失败: %w", err)
 	}
 
+	// Outbox indexes
+	outboxIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_attempt_at", Value: 1},
+			},
+		},
+	}
+
+	if _, err := m.outbox.Indexes().CreateMany(ctx, outboxIndexes); err != nil {
+		return fmt.Errorf("创建 notification_outbox 索引失败: %w", err)
+	}
+
+	// AI cache indexes; the TTL index lets MongoDB drop expired entries itself
+	aiCacheIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := m.aiCache.Indexes().CreateMany(ctx, aiCacheIndexes); err != nil {
+		return fmt.Errorf("创建 ai_cache 索引失败: %w", err)
+	}
+
+	aiUsageIndex := mongo.IndexModel{Keys: bson.D{{Key: "created_at", Value: -1}}}
+	if _, err := m.aiUsage.Indexes().CreateOne(ctx, aiUsageIndex); err != nil {
+		return fmt.Errorf("创建 ai_usage 索引失败: %w", err)
+	}
+
 	return nil
 }
 
@@ -139,6 +185,19 @@ func (m *MongoDB) UpdateThreadLastPage(link string, page int) error {
 	return err
 }
 
+// UpdateThreadStatus records the processing outcome and decision of a thread
+func (m *MongoDB) UpdateThreadStatus(link, status, aiSummary string, decision *Decision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.threads.UpdateOne(
+		ctx,
+		bson.M{"link": link},
+		bson.M{"$set": bson.M{"status": status, "ai_summary": aiSummary, "decision": decision}},
+	)
+	return err
+}
+
 // InsertComment inserts a new comment
 func (m *MongoDB) InsertComment(comment *Comment) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -164,12 +223,379 @@ func (m *MongoDB) FindComment(commentID string) (*Comment, error) {
 	return &comment, err
 }
 
+// UpdateCommentStatus records the processing outcome and decision of a comment
+func (m *MongoDB) UpdateCommentStatus(commentID, status, aiSummary string, decision *Decision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.comments.UpdateOne(
+		ctx,
+		bson.M{"comment_id": commentID},
+		bson.M{"$set": bson.M{"status": status, "ai_summary": aiSummary, "decision": decision}},
+	)
+	return err
+}
+
 // CommentExists checks if a comment exists
 func (m *MongoDB) CommentExists(commentID string) bool {
 	comment, err := m.FindComment(commentID)
 	return err == nil && comment != nil
 }
 
+// FindThreadByID finds a thread by its ObjectID hex string
+func (m *MongoDB) FindThreadByID(id string) (*Thread, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var thread Thread
+	err = m.threads.FindOne(ctx, bson.M{"_id": objectID}).Decode(&thread)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &thread, err
+}
+
+// ListThreads returns threads matching the query, newest first, and the total match count
+func (m *MongoDB) ListThreads(q ThreadQuery) ([]*Thread, int64, error) {
+	filter := bson.M{}
+	if q.Domain != "" {
+		filter["domain"] = containsRegex(q.Domain)
+	}
+	if q.Category != "" {
+		filter["category"] = equalFoldRegex(q.Category)
+	}
+	if q.Creator != "" {
+		filter["creator"] = equalFoldRegex(q.Creator)
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	if q.Search != "" {
+		filter["$or"] = bson.A{
+			bson.M{"title": containsRegex(q.Search)},
+			bson.M{"description": containsRegex(q.Search)},
+		}
+	}
+	if dateRange := timeRange(q.Since, q.Until); dateRange != nil {
+		filter["pub_date"] = dateRange
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := m.threads.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order := -1
+	if q.Oldest {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "pub_date", Value: order}, {Key: "_id", Value: order}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := m.threads.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var threads []*Thread
+	if err := cursor.All(ctx, &threads); err != nil {
+		return nil, 0, err
+	}
+	return threads, total, nil
+}
+
+// ListComments returns comments matching the query, newest first, and the total match count
+func (m *MongoDB) ListComments(q CommentQuery) ([]*Comment, int64, error) {
+	filter := bson.M{}
+	switch {
+	case q.ThreadURL != "" && q.Domain != "":
+		filter["thread_url"] = bson.M{"$eq": q.ThreadURL, "$regex": containsRegex(q.Domain)}
+	case q.ThreadURL != "":
+		filter["thread_url"] = q.ThreadURL
+	case q.Domain != "":
+		filter["thread_url"] = containsRegex(q.Domain)
+	}
+	if q.Author != "" {
+		filter["author"] = equalFoldRegex(q.Author)
+	}
+	if q.Role != "" {
+		filter["role"] = containsRegex(q.Role)
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	if q.Search != "" {
+		filter["message"] = containsRegex(q.Search)
+	}
+	if dateRange := timeRange(q.Since, q.Until); dateRange != nil {
+		filter["created_at"] = dateRange
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := m.comments.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order := -1
+	if q.Oldest {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: order}, {Key: "_id", Value: order}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := m.comments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*Comment
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// containsRegex matches values containing s, case-insensitively
+func containsRegex(s string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(s), Options: "i"}
+}
+
+// equalFoldRegex matches values equal to s, case-insensitively
+func equalFoldRegex(s string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(s) + "$", Options: "i"}
+}
+
+// timeRange builds a [since, until) filter, or nil when both bounds are zero
+func timeRange(since, until time.Time) bson.M {
+	if since.IsZero() && until.IsZero() {
+		return nil
+	}
+	dateRange := bson.M{}
+	if !since.IsZero() {
+		dateRange["$gte"] = since
+	}
+	if !until.IsZero() {
+		dateRange["$lt"] = until
+	}
+	return dateRange
+}
+
+// InsertOutbox inserts a new outbox entry
+func (m *MongoDB) InsertOutbox(entry *OutboxEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := m.outbox.InsertOne(ctx, entry)
+	if err != nil {
+		return err
+	}
+	entry.ID = result.InsertedID
+	return nil
+}
+
+// UpdateOutbox updates the delivery state of an outbox entry
+func (m *MongoDB) UpdateOutbox(entry *OutboxEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.outbox.UpdateOne(
+		ctx,
+		bson.M{"_id": entry.ID},
+		bson.M{"$set": bson.M{
+			"targets":         entry.Targets,
+			"status":          entry.Status,
+			"attempts":        entry.Attempts,
+			"last_error":      entry.LastError,
+			"next_attempt_at": entry.NextAttemptAt,
+			"updated_at":      entry.UpdatedAt,
+		}},
+	)
+	return err
+}
+
+// ListDueOutbox returns pending outbox entries whose next attempt is due
+func (m *MongoDB) ListDueOutbox(now time.Time, limit int) ([]*OutboxEntry, error) {
+	filter := bson.M{
+		"status":          OutboxPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return m.findOutbox(filter, opts)
+}
+
+// ListOutbox returns outbox entries, optionally filtered by status, newest first
+func (m *MongoDB) ListOutbox(status string, limit int) ([]*OutboxEntry, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	return m.findOutbox(filter, opts)
+}
+
+// RequeueOutbox moves an outbox entry back to pending for an immediate retry
+func (m *MongoDB) RequeueOutbox(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("无效的 outbox ID: %s", id)
+	}
+
+	now := time.Now().UTC()
+	result, err := m.outbox.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{
+			"status":          OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("outbox 记录不存在: %s", id)
+	}
+	return nil
+}
+
+// FindAICache returns the cached AI result for key, or nil when there is none.
+// Expired entries are returned too; callers check ExpiresAt.
+func (m *MongoDB) FindAICache(key string) (*AICacheEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var entry AICacheEntry
+	err := m.aiCache.FindOne(ctx, bson.M{"key": key}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpsertAICache stores an AI result, replacing any entry with the same key
+func (m *MongoDB) UpsertAICache(entry *AICacheEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.aiCache.ReplaceOne(ctx, bson.M{"key": entry.Key}, entry, options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeleteExpiredAICache removes cache entries that expired before now
+func (m *MongoDB) DeleteExpiredAICache(now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := m.aiCache.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": now}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// InsertAIUsage records the usage of an AI request
+func (m *MongoDB) InsertAIUsage(usage *AIUsage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := m.aiUsage.InsertOne(ctx, usage)
+	return err
+}
+
+// SummarizeAIUsage aggregates AI usage created in [since, until) per UTC day,
+// provider and model, newest day first
+func (m *MongoDB) SummarizeAIUsage(since, until time.Time) ([]*AIUsageSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since, "$lt": until}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"date":     bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+				"provider": "$provider",
+				"model":    "$model",
+			},
+			"calls":             bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"cost":              bson.M{"$sum": "$cost"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"date":              "$_id.date",
+			"provider":          "$_id.provider",
+			"model":             "$_id.model",
+			"calls":             1,
+			"prompt_tokens":     1,
+			"completion_tokens": 1,
+			"cost":              1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "provider", Value: 1}, {Key: "model", Value: 1}}}},
+	}
+
+	cursor, err := m.aiUsage.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var summaries []*AIUsageSummary
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// findOutbox runs an outbox query and decodes the results
+func (m *MongoDB) findOutbox(filter bson.M, opts *options.FindOptions) ([]*OutboxEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := m.outbox.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*OutboxEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
 // Disconnect closes the MongoDB connection
 func (m *MongoDB) Disconnect() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
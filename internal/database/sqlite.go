@@ -12,7 +12,10 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -61,7 +64,11 @@ func (s *SQLite) createTables() error {
 			creator TEXT NOT NULL,
 			pub_date DATETIME NOT NULL,
 			created_at DATETIME NOT NULL,
-			last_page INTEGER DEFAULT 0
+			last_page INTEGER DEFAULT 0,
+			status TEXT NOT NULL DEFAULT '',
+			ai_summary TEXT NOT NULL DEFAULT '',
+			decision TEXT NOT NULL DEFAULT '',
+			offer TEXT NOT NULL DEFAULT ''
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_threads_link ON threads(link)`,
 		`CREATE INDEX IF NOT EXISTS idx_threads_pub_date ON threads(pub_date DESC)`,
@@ -70,13 +77,52 @@ func (s *SQLite) createTables() error {
 			comment_id TEXT NOT NULL UNIQUE,
 			thread_url TEXT NOT NULL,
 			author TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT '',
 			message TEXT NOT NULL,
 			created_at DATETIME NOT NULL,
 			created_at_recorded DATETIME NOT NULL,
-			url TEXT NOT NULL
+			url TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT '',
+			ai_summary TEXT NOT NULL DEFAULT '',
+			decision TEXT NOT NULL DEFAULT ''
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_comments_comment_id ON comments(comment_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_comments_thread_url ON comments(thread_url, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS notification_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			thread_link TEXT NOT NULL,
+			comment_id TEXT NOT NULL DEFAULT '',
+			ai_description TEXT NOT NULL DEFAULT '',
+			targets TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			next_attempt_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_status_next ON notification_outbox(status, next_attempt_at)`,
+		`CREATE TABLE IF NOT EXISTS ai_cache (
+			key TEXT PRIMARY KEY,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			result TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_cache_expires_at ON ai_cache(expires_at)`,
+		`CREATE TABLE IF NOT EXISTS ai_usage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			prompt_tokens INTEGER NOT NULL DEFAULT 0,
+			completion_tokens INTEGER NOT NULL DEFAULT 0,
+			cost REAL NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_usage_created_at ON ai_usage(created_at)`,
 	}
 
 	for _, query := range queries {
@@ -85,14 +131,78 @@ func (s *SQLite) createTables() error {
 		}
 	}
 
+	// Columns added after the initial schema; existing databases need them too
+	migrations := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"comments", "role", "TEXT NOT NULL DEFAULT ''"},
+		{"threads", "status", "TEXT NOT NULL DEFAULT ''"},
+		{"threads", "ai_summary", "TEXT NOT NULL DEFAULT ''"},
+		{"comments", "status", "TEXT NOT NULL DEFAULT ''"},
+		{"comments", "ai_summary", "TEXT NOT NULL DEFAULT ''"},
+		{"threads", "decision", "TEXT NOT NULL DEFAULT ''"},
+		{"comments", "decision", "TEXT NOT NULL DEFAULT ''"},
+		{"threads", "offer", "TEXT NOT NULL DEFAULT ''"},
+		{"notification_outbox", "targets", "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, m := range migrations {
+		if err := s.addColumnIfMissing(m.table, m.column, m.definition); err != nil {
+			return fmt.Errorf("迁移 %s.%s 失败: %w", m.table, m.column, err)
+		}
+	}
+
 	return nil
 }
 
+// addColumnIfMissing adds a column to an existing table when it is not present yet
+func (s *SQLite) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 // InsertThread inserts a new thread
 func (s *SQLite) InsertThread(thread *Thread) error {
 	query := `INSERT OR IGNORE INTO threads 
-		(domain, category, title, link, description, creator, pub_date, created_at, last_page) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		(domain, category, title, link, description, creator, pub_date, created_at, last_page, status, ai_summary, decision, offer) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	decision, err := encodeDecision(thread.Decision)
+	if err != nil {
+		return err
+	}
+	offer, err := encodeOffer(thread.Offer)
+	if err != nil {
+		return err
+	}
 
 	result, err := s.db.Exec(query,
 		thread.Domain,
@@ -101,9 +211,13 @@ func (s *SQLite) InsertThread(thread *Thread) error {
 		thread.Link,
 		thread.Description,
 		thread.Creator,
-		thread.PubDate,
-		thread.CreatedAt,
+		thread.PubDate.UTC(),
+		thread.CreatedAt.UTC(),
 		thread.LastPage,
+		thread.Status,
+		thread.AISummary,
+		decision,
+		offer,
 	)
 
 	if err != nil {
@@ -118,38 +232,157 @@ func (s *SQLite) InsertThread(thread *Thread) error {
 	return nil
 }
 
-// FindThread finds a thread by link
-func (s *SQLite) FindThread(link string) (*Thread, error) {
-	query := `SELECT id, domain, category, title, link, description, creator, 
-		pub_date, created_at, last_page FROM threads WHERE link = ?`
+// UpdateThreadStatus records the processing outcome and decision of a thread
+func (s *SQLite) UpdateThreadStatus(link, status, aiSummary string, decision *Decision) error {
+	encoded, err := encodeDecision(decision)
+	if err != nil {
+		return err
+	}
 
+	_, err = s.db.Exec(`UPDATE threads SET status = ?, ai_summary = ?, decision = ? WHERE link = ?`,
+		status, aiSummary, encoded, link)
+	return err
+}
+
+// threadColumns lists the columns read by scanThread
+const threadColumns = `id, domain, category, title, link, description, creator, 
+	pub_date, created_at, last_page, status, ai_summary, decision, offer`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanThread scans a row selected with threadColumns
+func scanThread(row rowScanner) (*Thread, error) {
 	var thread Thread
-	var pubDate, createdAt string
+	var id int64
+	var decision, offer string
 
-	err := s.db.QueryRow(query, link).Scan(
-		&thread.ID,
+	err := row.Scan(
+		&id,
 		&thread.Domain,
 		&thread.Category,
 		&thread.Title,
 		&thread.Link,
 		&thread.Description,
 		&thread.Creator,
-		&pubDate,
-		&createdAt,
+		&thread.PubDate,
+		&thread.CreatedAt,
 		&thread.LastPage,
+		&thread.Status,
+		&thread.AISummary,
+		&decision,
+		&offer,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.Decision, err = decodeDecision(decision); err != nil {
+		return nil, err
+	}
+	if thread.Offer, err = decodeOffer(offer); err != nil {
+		return nil, err
+	}
+
+	thread.ID = id
+	return &thread, nil
+}
+
+// FindThread finds a thread by link
+func (s *SQLite) FindThread(link string) (*Thread, error) {
+	query := `SELECT ` + threadColumns + ` FROM threads WHERE link = ?`
+
+	thread, err := scanThread(s.db.QueryRow(query, link))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return thread, err
+}
+
+// FindThreadByID finds a thread by its database ID
+func (s *SQLite) FindThreadByID(id string) (*Thread, error) {
+	threadID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
 
+	query := `SELECT ` + threadColumns + ` FROM threads WHERE id = ?`
+
+	thread, err := scanThread(s.db.QueryRow(query, threadID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
+	return thread, err
+}
+
+// ListThreads returns threads matching the query, newest first, and the total match count
+func (s *SQLite) ListThreads(q ThreadQuery) ([]*Thread, int64, error) {
+	var where []string
+	var args []interface{}
+
+	if q.Domain != "" {
+		where = append(where, "domain LIKE ? ESCAPE '\\'")
+		args = append(args, containsPattern(q.Domain))
+	}
+	if q.Category != "" {
+		where = append(where, "category = ? COLLATE NOCASE")
+		args = append(args, q.Category)
+	}
+	if q.Creator != "" {
+		where = append(where, "creator = ? COLLATE NOCASE")
+		args = append(args, q.Creator)
+	}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+	if q.Search != "" {
+		where = append(where, "(title LIKE ? ESCAPE '\\' OR description LIKE ? ESCAPE '\\')")
+		args = append(args, containsPattern(q.Search), containsPattern(q.Search))
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "datetime(pub_date) >= datetime(?)")
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "datetime(pub_date) < datetime(?)")
+		args = append(args, q.Until.UTC())
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM threads`+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "DESC"
+	if q.Oldest {
+		order = "ASC"
+	}
+	query := `SELECT ` + threadColumns + ` FROM threads` + whereClause +
+		` ORDER BY datetime(pub_date) ` + order + `, id ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, append(args, q.Limit, q.Offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	defer rows.Close()
 
-	thread.PubDate, _ = time.Parse("2006-01-02 15:04:05", pubDate)
-	thread.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	var threads []*Thread
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		threads = append(threads, thread)
+	}
 
-	return &thread, nil
+	return threads, total, rows.Err()
 }
 
 // UpdateThreadLastPage updates the last_page field
@@ -162,17 +395,26 @@ func (s *SQLite) UpdateThreadLastPage(link string, page int) error {
 // InsertComment inserts a new comment
 func (s *SQLite) InsertComment(comment *Comment) error {
 	query := `INSERT OR IGNORE INTO comments 
-		(comment_id, thread_url, author, message, created_at, created_at_recorded, url) 
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+		(comment_id, thread_url, author, role, message, created_at, created_at_recorded, url, status, ai_summary, decision) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	decision, err := encodeDecision(comment.Decision)
+	if err != nil {
+		return err
+	}
 
 	result, err := s.db.Exec(query,
 		comment.CommentID,
 		comment.ThreadURL,
 		comment.Author,
+		comment.Role,
 		comment.Message,
-		comment.CreatedAt,
-		comment.CreatedAtRecorded,
+		comment.CreatedAt.UTC(),
+		comment.CreatedAtRecorded.UTC(),
 		comment.URL,
+		comment.Status,
+		comment.AISummary,
+		decision,
 	)
 
 	if err != nil {
@@ -187,36 +429,192 @@ func (s *SQLite) InsertComment(comment *Comment) error {
 	return nil
 }
 
-// FindComment finds a comment by comment_id
-func (s *SQLite) FindComment(commentID string) (*Comment, error) {
-	query := `SELECT id, comment_id, thread_url, author, message, 
-		created_at, created_at_recorded, url FROM comments WHERE comment_id = ?`
+// UpdateCommentStatus records the processing outcome and decision of a comment
+func (s *SQLite) UpdateCommentStatus(commentID, status, aiSummary string, decision *Decision) error {
+	encoded, err := encodeDecision(decision)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`UPDATE comments SET status = ?, ai_summary = ?, decision = ? WHERE comment_id = ?`,
+		status, aiSummary, encoded, commentID)
+	return err
+}
+
+// encodeDecision serializes a decision for a TEXT column; nil becomes an empty string
+func encodeDecision(decision *Decision) (string, error) {
+	if decision == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return "", fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeDecision parses a decision TEXT column; an empty string yields nil
+func decodeDecision(data string) (*Decision, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var decision Decision
+	if err := json.Unmarshal([]byte(data), &decision); err != nil {
+		return nil, fmt.Errorf("解析决策记录失败: %w", err)
+	}
+	return &decision, nil
+}
 
+// encodeOffer serializes an extracted offer for a TEXT column; nil becomes an empty string
+func encodeOffer(offer *Offer) (string, error) {
+	if offer == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(offer)
+	if err != nil {
+		return "", fmt.Errorf("序列化报价信息失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeOffer parses an offer TEXT column; an empty string yields nil
+func decodeOffer(data string) (*Offer, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var offer Offer
+	if err := json.Unmarshal([]byte(data), &offer); err != nil {
+		return nil, fmt.Errorf("解析报价信息失败: %w", err)
+	}
+	return &offer, nil
+}
+
+// commentColumns lists the columns read by scanComment
+const commentColumns = `id, comment_id, thread_url, author, role, message, 
+	created_at, created_at_recorded, url, status, ai_summary, decision`
+
+// scanComment scans a row selected with commentColumns
+func scanComment(row rowScanner) (*Comment, error) {
 	var comment Comment
-	var createdAt, createdAtRecorded string
+	var id int64
+	var decision string
 
-	err := s.db.QueryRow(query, commentID).Scan(
-		&comment.ID,
+	err := row.Scan(
+		&id,
 		&comment.CommentID,
 		&comment.ThreadURL,
 		&comment.Author,
+		&comment.Role,
 		&comment.Message,
-		&createdAt,
-		&createdAtRecorded,
+		&comment.CreatedAt,
+		&comment.CreatedAtRecorded,
 		&comment.URL,
+		&comment.Status,
+		&comment.AISummary,
+		&decision,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	if comment.Decision, err = decodeDecision(decision); err != nil {
+		return nil, err
+	}
+
+	comment.ID = id
+	return &comment, nil
+}
+
+// FindComment finds a comment by comment_id
+func (s *SQLite) FindComment(commentID string) (*Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE comment_id = ?`
+
+	comment, err := scanComment(s.db.QueryRow(query, commentID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
+	return comment, err
+}
+
+// ListComments returns comments matching the query, newest first, and the total match count
+func (s *SQLite) ListComments(q CommentQuery) ([]*Comment, int64, error) {
+	var where []string
+	var args []interface{}
+
+	if q.ThreadURL != "" {
+		where = append(where, "thread_url = ?")
+		args = append(args, q.ThreadURL)
+	}
+	if q.Domain != "" {
+		where = append(where, "thread_url LIKE ? ESCAPE '\\'")
+		args = append(args, containsPattern(q.Domain))
+	}
+	if q.Author != "" {
+		where = append(where, "author = ? COLLATE NOCASE")
+		args = append(args, q.Author)
+	}
+	if q.Role != "" {
+		where = append(where, "role LIKE ? ESCAPE '\\'")
+		args = append(args, containsPattern(q.Role))
+	}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+	if q.Search != "" {
+		where = append(where, "message LIKE ? ESCAPE '\\'")
+		args = append(args, containsPattern(q.Search))
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "datetime(created_at) >= datetime(?)")
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "datetime(created_at) < datetime(?)")
+		args = append(args, q.Until.UTC())
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM comments`+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "DESC"
+	if q.Oldest {
+		order = "ASC"
+	}
+	query := `SELECT ` + commentColumns + ` FROM comments` + whereClause +
+		` ORDER BY datetime(created_at) ` + order + `, id ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, append(args, q.Limit, q.Offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		comments = append(comments, comment)
 	}
 
-	comment.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
-	comment.CreatedAtRecorded, _ = time.Parse("2006-01-02 15:04:05", createdAtRecorded)
+	return comments, total, rows.Err()
+}
 
-	return &comment, nil
+// likeEscaper escapes the LIKE wildcards for patterns used with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns a LIKE pattern matching values that contain s
+// literally, like containsRegex does for MongoDB
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
 }
 
 // CommentExists checks if a comment exists
@@ -225,6 +623,226 @@ func (s *SQLite) CommentExists(commentID string) bool {
 	return err == nil && comment != nil
 }
 
+// InsertOutbox inserts a new outbox entry
+func (s *SQLite) InsertOutbox(entry *OutboxEntry) error {
+	query := `INSERT INTO notification_outbox 
+		(channel, kind, thread_link, comment_id, ai_description, targets, status, attempts, 
+		last_error, next_attempt_at, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.Exec(query,
+		entry.Channel,
+		entry.Kind,
+		entry.ThreadLink,
+		entry.CommentID,
+		entry.AIDescription,
+		strings.Join(entry.Targets, ","),
+		entry.Status,
+		entry.Attempts,
+		entry.LastError,
+		entry.NextAttemptAt.UTC(),
+		entry.CreatedAt.UTC(),
+		entry.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err == nil && id > 0 {
+		entry.ID = id
+	}
+
+	return nil
+}
+
+// UpdateOutbox updates the delivery state of an outbox entry
+func (s *SQLite) UpdateOutbox(entry *OutboxEntry) error {
+	query := `UPDATE notification_outbox SET targets = ?, status = ?, attempts = ?, last_error = ?, 
+		next_attempt_at = ?, updated_at = ? WHERE id = ?`
+
+	_, err := s.db.Exec(query,
+		strings.Join(entry.Targets, ","),
+		entry.Status,
+		entry.Attempts,
+		entry.LastError,
+		entry.NextAttemptAt.UTC(),
+		entry.UpdatedAt.UTC(),
+		entry.ID,
+	)
+	return err
+}
+
+// ListDueOutbox returns pending outbox entries whose next attempt is due
+func (s *SQLite) ListDueOutbox(now time.Time, limit int) ([]*OutboxEntry, error) {
+	query := `SELECT id, channel, kind, thread_link, comment_id, ai_description, targets, status, 
+		attempts, last_error, next_attempt_at, created_at, updated_at 
+		FROM notification_outbox WHERE status = ? AND next_attempt_at <= ? 
+		ORDER BY next_attempt_at LIMIT ?`
+
+	return s.queryOutbox(query, OutboxPending, now.UTC(), limit)
+}
+
+// ListOutbox returns outbox entries, optionally filtered by status, newest first
+func (s *SQLite) ListOutbox(status string, limit int) ([]*OutboxEntry, error) {
+	query := `SELECT id, channel, kind, thread_link, comment_id, ai_description, targets, status, 
+		attempts, last_error, next_attempt_at, created_at, updated_at 
+		FROM notification_outbox WHERE (? = '' OR status = ?) 
+		ORDER BY updated_at DESC LIMIT ?`
+
+	return s.queryOutbox(query, status, status, limit)
+}
+
+// RequeueOutbox moves an outbox entry back to pending for an immediate retry
+func (s *SQLite) RequeueOutbox(id string) error {
+	entryID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的 outbox ID: %s", id)
+	}
+
+	now := time.Now().UTC()
+	result, err := s.db.Exec(`UPDATE notification_outbox SET status = ?, attempts = 0, 
+		next_attempt_at = ?, updated_at = ? WHERE id = ?`, OutboxPending, now, now, entryID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("outbox 记录不存在: %s", id)
+	}
+	return nil
+}
+
+// queryOutbox runs an outbox query and scans the resulting rows
+func (s *SQLite) queryOutbox(query string, args ...interface{}) ([]*OutboxEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*OutboxEntry
+	for rows.Next() {
+		var entry OutboxEntry
+		var id int64
+		var targets string
+		if err := rows.Scan(
+			&id,
+			&entry.Channel,
+			&entry.Kind,
+			&entry.ThreadLink,
+			&entry.CommentID,
+			&entry.AIDescription,
+			&targets,
+			&entry.Status,
+			&entry.Attempts,
+			&entry.LastError,
+			&entry.NextAttemptAt,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entry.ID = id
+		if targets != "" {
+			entry.Targets = strings.Split(targets, ",")
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// FindAICache returns the cached AI result for key, or nil when there is none.
+// Expired entries are returned too; callers check ExpiresAt.
+func (s *SQLite) FindAICache(key string) (*AICacheEntry, error) {
+	var entry AICacheEntry
+	err := s.db.QueryRow(`SELECT key, provider, model, result, created_at, expires_at 
+		FROM ai_cache WHERE key = ?`, key).Scan(
+		&entry.Key,
+		&entry.Provider,
+		&entry.Model,
+		&entry.Result,
+		&entry.CreatedAt,
+		&entry.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// UpsertAICache stores an AI result, replacing any entry with the same key
+func (s *SQLite) UpsertAICache(entry *AICacheEntry) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO ai_cache 
+		(key, provider, model, result, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.Key,
+		entry.Provider,
+		entry.Model,
+		entry.Result,
+		entry.CreatedAt.UTC(),
+		entry.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// DeleteExpiredAICache removes cache entries that expired before now
+func (s *SQLite) DeleteExpiredAICache(now time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM ai_cache WHERE datetime(expires_at) < datetime(?)`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// InsertAIUsage records the usage of an AI request
+func (s *SQLite) InsertAIUsage(usage *AIUsage) error {
+	_, err := s.db.Exec(`INSERT INTO ai_usage 
+		(provider, model, prompt_tokens, completion_tokens, cost, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		usage.Provider,
+		usage.Model,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.Cost,
+		usage.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// SummarizeAIUsage aggregates AI usage created in [since, until) per UTC day,
+// provider and model, newest day first
+func (s *SQLite) SummarizeAIUsage(since, until time.Time) ([]*AIUsageSummary, error) {
+	rows, err := s.db.Query(`SELECT date(created_at) AS day, provider, model, COUNT(*),
+		SUM(prompt_tokens), SUM(completion_tokens), SUM(cost)
+		FROM ai_usage WHERE datetime(created_at) >= datetime(?) AND datetime(created_at) < datetime(?)
+		GROUP BY day, provider, model ORDER BY day DESC, provider, model`, since.UTC(), until.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*AIUsageSummary
+	for rows.Next() {
+		var summary AIUsageSummary
+		if err := rows.Scan(
+			&summary.Date,
+			&summary.Provider,
+			&summary.Model,
+			&summary.Calls,
+			&summary.PromptTokens,
+			&summary.CompletionTokens,
+			&summary.Cost,
+		); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+	return summaries, rows.Err()
+}
+
 // Disconnect closes the SQLite connection
 func (s *SQLite) Disconnect() error {
 	if err := s.db.Close(); err != nil {
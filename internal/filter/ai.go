@@ -16,26 +16,29 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
 // AIFilter filters content using Cloudflare Workers AI
 type AIFilter struct {
-	accountID string
-	token     string
-	model     string
-	client    *http.Client
+	accountID  string
+	token      string
+	model      string
+	outputMode string
+	client     *http.Client
 }
 
-// NewAIFilter creates a new AI filter
-func NewAIFilter(accountID, token, model string) *AIFilter {
+// NewAIFilter creates a new AI filter. outputMode is config.AIOutputText or config.AIOutputJSON.
+func NewAIFilter(accountID, token, model, outputMode string) *AIFilter {
 	return &AIFilter{
-		accountID: accountID,
-		token:     token,
-		model:     model,
+		accountID:  accountID,
+		token:      token,
+		model:      model,
+		outputMode: outputMode,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -50,7 +53,14 @@ type Message struct {
 
 // AIRequest represents the request to Cloudflare AI API
 type AIRequest struct {
-	Messages []Message `json:"messages"`
+	Messages       []Message         `json:"messages"`
+	ResponseFormat *AIResponseFormat `json:"response_format,omitempty"`
+}
+
+// AIResponseFormat enables Workers AI JSON mode
+type AIResponseFormat struct {
+	Type       string      `json:"type"` // "json_schema"
+	JSONSchema interface{} `json:"json_schema"`
 }
 
 // AIResponse represents the response from Cloudflare AI API
@@ -61,6 +71,13 @@ type AIResponse struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		// Response is a string, or an object in JSON mode
+		Response json.RawMessage `json:"response"`
+		Usage    struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
 	} `json:"result"`
 	Success bool     `json:"success"`
 	Errors  []string `json:"errors"`
@@ -68,24 +85,48 @@ type AIResponse struct {
 
 // Filter sends content to AI and returns the filtered result
 func (f *AIFilter) Filter(content string, prompt string) (string, error) {
+	result, _, err := f.filterWithUsage(content, prompt)
+	return result, err
+}
+
+// filterWithUsage is Filter, also returning the token usage of the request
+func (f *AIFilter) filterWithUsage(content string, prompt string) (string, Usage, error) {
+	start := time.Now()
+	result, usage, err := f.chat(content, prompt)
+	metrics.ObserveAIRequest("cloudflare", f.model, err, start)
+	if err != nil {
+		return "", usage, err
+	}
+
+	result = normalizeResult(result)
+
+	log.Debugf("AI 过滤结果: %s", result)
+	return result, usage, nil
+}
+
+// chat sends the prompt and content to Workers AI and returns the raw reply
+func (f *AIFilter) chat(content string, prompt string) (string, Usage, error) {
 	apiURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/run/%s",
 		f.accountID, f.model)
 
 	req := AIRequest{
 		Messages: []Message{
-			{Role: "system", Content: prompt},
+			{Role: "system", Content: verdictPrompt(prompt, f.outputMode)},
 			{Role: "user", Content: content},
 		},
 	}
+	if f.outputMode == config.AIOutputJSON {
+		req.ResponseFormat = &AIResponseFormat{Type: "json_schema", JSONSchema: verdictSchema}
+	}
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("无法序列化请求: %w", err)
+		return "", Usage{}, fmt.Errorf("无法序列化请求: %w", err)
 	}
 
 	httpReq, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("无法创建请求: %w", err)
+		return "", Usage{}, fmt.Errorf("无法创建请求: %w", err)
 	}
 
 	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", f.token))
@@ -93,42 +134,48 @@ func (f *AIFilter) Filter(content string, prompt string) (string, error) {
 
 	resp, err := f.client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("AI API 请求失败: %w", err)
+		return "", Usage{}, fmt.Errorf("AI API 请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("无法读取响应: %w", err)
+		return "", Usage{}, fmt.Errorf("无法读取响应: %w", err)
 	}
 
 	var aiResp AIResponse
 	if err := json.Unmarshal(body, &aiResp); err != nil {
-		return "", fmt.Errorf("无法解析响应: %w", err)
+		return "", Usage{}, fmt.Errorf("无法解析响应: %w", err)
 	}
 
 	if !aiResp.Success || len(aiResp.Errors) > 0 {
-		return "", fmt.Errorf("AI API 返回错误: %v", aiResp.Errors)
+		return "", Usage{}, fmt.Errorf("AI API 返回错误: %v", aiResp.Errors)
 	}
 
-	if len(aiResp.Result.Choices) == 0 {
-		return "", fmt.Errorf("AI API 返回空结果")
+	usage := Usage{
+		PromptTokens:     aiResp.Result.Usage.PromptTokens,
+		CompletionTokens: aiResp.Result.Usage.CompletionTokens,
 	}
+	metrics.AddAITokens("cloudflare", f.model, usage.PromptTokens, usage.CompletionTokens)
 
-	result := aiResp.Result.Choices[0].Message.Content
-
-	// Extract content before "END" marker
-	if idx := strings.Index(result, "END"); idx >= 0 {
-		result = result[:idx]
+	if len(aiResp.Result.Choices) > 0 {
+		return aiResp.Result.Choices[0].Message.Content, usage, nil
 	}
 
-	result = strings.TrimSpace(result)
+	// Models without chat completions answer in result.response
+	if len(aiResp.Result.Response) > 0 && string(aiResp.Result.Response) != "null" {
+		var text string
+		if err := json.Unmarshal(aiResp.Result.Response, &text); err == nil {
+			return text, usage, nil
+		}
+		return string(aiResp.Result.Response), usage, nil
+	}
 
-	log.Debugf("AI 过滤结果: %s", result)
-	return result, nil
+	return "", usage, fmt.Errorf("AI API 返回空结果")
 }
 
-// IsValidResult checks if the AI result is valid (not "FALSE")
+// IsValidResult checks if the AI result accepts the content: a JSON verdict
+// marked relevant, or a text reply that does not start with "FALSE"
 func (f *AIFilter) IsValidResult(result string) bool {
-	return strings.ToUpper(strings.TrimSpace(result)) != "FALSE"
+	return isRelevant(result)
 }
@@ -0,0 +1,195 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Anthropic Messages API Filter"
+//   Timestamp: "2025-12-15T14:10:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Claude models were only reachable through OpenAI-compatible proxies"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Interface Segregation"
+//   Quality_Check: "Native Messages API request/response types with typed API errors"
+// }}
+
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// anthropicVersion is the Messages API version sent in the anthropic-version header
+const anthropicVersion = "2023-06-01"
+
+// AnthropicFilter filters content using the Anthropic Messages API
+type AnthropicFilter struct {
+	apiURL     string
+	apiKey     string
+	model      string
+	maxTokens  int
+	outputMode string
+	client     *http.Client
+}
+
+// NewAnthropicFilter creates a new Anthropic filter. An empty apiURL uses the
+// public endpoint. outputMode is config.AIOutputText or config.AIOutputJSON.
+func NewAnthropicFilter(apiURL, apiKey, model string, maxTokens int, outputMode string) *AnthropicFilter {
+	if apiURL == "" {
+		apiURL = config.DefaultAnthropicAPIURL
+	}
+	if maxTokens <= 0 {
+		maxTokens = config.DefaultAnthropicMaxTokens
+	}
+	return &AnthropicFilter{
+		apiURL:     apiURL,
+		apiKey:     apiKey,
+		model:      model,
+		maxTokens:  maxTokens,
+		outputMode: outputMode,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// AnthropicMessage represents a message of the Messages API
+type AnthropicMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// AnthropicRequest represents the request to the Messages API
+type AnthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []AnthropicMessage `json:"messages"`
+}
+
+// AnthropicResponse represents the response from the Messages API
+type AnthropicResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// AnthropicError represents an error response of the Messages API
+type AnthropicError struct {
+	Type  string `json:"type"` // "error"
+	Error struct {
+		Type    string `json:"type"` // e.g. "overloaded_error", "rate_limit_error"
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Filter sends content to the Messages API and returns the filtered result
+func (f *AnthropicFilter) Filter(content string, prompt string) (string, error) {
+	result, _, err := f.filterWithUsage(content, prompt)
+	return result, err
+}
+
+// filterWithUsage is Filter, also returning the token usage of the request
+func (f *AnthropicFilter) filterWithUsage(content string, prompt string) (string, Usage, error) {
+	start := time.Now()
+	result, usage, err := f.send(content, prompt)
+	metrics.ObserveAIRequest("anthropic", f.model, err, start)
+	if err != nil {
+		return "", usage, err
+	}
+
+	result = normalizeResult(result)
+
+	log.Debugf("Anthropic AI 过滤结果: %s", result)
+	return result, usage, nil
+}
+
+// send posts the prompt and content to the Messages API and returns the raw reply
+func (f *AnthropicFilter) send(content string, prompt string) (string, Usage, error) {
+	req := AnthropicRequest{
+		Model:     f.model,
+		MaxTokens: f.maxTokens,
+		System:    verdictPrompt(prompt, f.outputMode),
+		Messages:  []AnthropicMessage{{Role: "user", Content: content}},
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("无法序列化请求: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", f.apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("无法创建请求: %w", err)
+	}
+
+	httpReq.Header.Set("x-api-key", f.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(httpReq)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("Anthropic API 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("无法读取响应: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr AnthropicError
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return "", Usage{}, fmt.Errorf("Anthropic API 返回错误状态码 %d (%s): %s", resp.StatusCode, apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return "", Usage{}, fmt.Errorf("Anthropic API 返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var anthropicResp AnthropicResponse
+	if err := json.Unmarshal(body, &anthropicResp); err != nil {
+		return "", Usage{}, fmt.Errorf("无法解析响应: %w", err)
+	}
+
+	log.Debugf("Token 使用量 - Input: %d, Output: %d",
+		anthropicResp.Usage.InputTokens,
+		anthropicResp.Usage.OutputTokens)
+	usage := Usage{
+		PromptTokens:     anthropicResp.Usage.InputTokens,
+		CompletionTokens: anthropicResp.Usage.OutputTokens,
+	}
+	metrics.AddAITokens("anthropic", f.model, usage.PromptTokens, usage.CompletionTokens)
+
+	var text strings.Builder
+	for _, block := range anthropicResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", usage, fmt.Errorf("Anthropic API 返回空结果 (stop_reason: %s)", anthropicResp.StopReason)
+	}
+
+	return text.String(), usage, nil
+}
+
+// IsValidResult checks if the AI result accepts the content: a JSON verdict
+// marked relevant, or a text reply that does not start with "FALSE"
+func (f *AnthropicFilter) IsValidResult(result string) bool {
+	return isRelevant(result)
+}
@@ -0,0 +1,159 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Result Cache"
+//   Timestamp: "2025-12-14T09:40:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Reloads, retries and cross-posted offers re-sent identical content to the AI provider"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Decorator Pattern"
+//   Quality_Check: "Cache failures never fail filtering; errors are not cached; hit rate reported"
+// }}
+
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// AICacheStore persists cached AI results; database.Database implements it
+type AICacheStore interface {
+	FindAICache(key string) (*database.AICacheEntry, error)
+	UpsertAICache(entry *database.AICacheEntry) error
+	DeleteExpiredAICache(now time.Time) (int64, error)
+}
+
+// aiCachePurgeInterval is how often expired entries are removed
+const aiCachePurgeInterval = time.Hour
+
+// Lookups since the process started, across filter rebuilds on reload
+var aiCacheHits, aiCacheMisses atomic.Int64
+
+// AICacheStats reports how often AI results were served from the cache
+type AICacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 0 to 1, 0 before the first lookup
+}
+
+// GetAICacheStats returns the cache lookups since the process started
+func GetAICacheStats() AICacheStats {
+	stats := AICacheStats{Hits: aiCacheHits.Load(), Misses: aiCacheMisses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// CachedAIFilter serves repeated AI requests from the database
+type CachedAIFilter struct {
+	inner      AIFilterInterface
+	store      AICacheStore
+	provider   string
+	model      string
+	outputMode string
+	ttl        time.Duration
+	lastPurge  atomic.Int64 // unix nanoseconds
+}
+
+// NewCachedAIFilter wraps inner with a cache whose entries live for ttl
+func NewCachedAIFilter(inner AIFilterInterface, store AICacheStore, provider, model, outputMode string, ttl time.Duration) *CachedAIFilter {
+	return &CachedAIFilter{
+		inner:      inner,
+		store:      store,
+		provider:   provider,
+		model:      model,
+		outputMode: outputMode,
+		ttl:        ttl,
+	}
+}
+
+// Filter returns the cached result for the same provider, model, output mode,
+// prompt and content, or asks the wrapped filter and caches its answer.
+// Failed requests are not cached.
+func (f *CachedAIFilter) Filter(content string, prompt string) (string, error) {
+	if result, ok := f.cached(content, prompt); ok {
+		return result, nil
+	}
+	return f.fetch(content, prompt)
+}
+
+// cached returns the unexpired cached result, counting the hit or miss
+func (f *CachedAIFilter) cached(content, prompt string) (string, bool) {
+	now := time.Now().UTC()
+	f.purgeExpired(now)
+
+	key := f.key(content, prompt)
+	entry, err := f.store.FindAICache(key)
+	if err != nil {
+		log.Warnf("查询 AI 缓存失败: %v", err)
+	}
+	if entry != nil && entry.ExpiresAt.After(now) {
+		aiCacheHits.Add(1)
+		metrics.ObserveAICache(f.provider, f.model, true)
+		log.Debugf("AI 缓存命中: %s", key[:12])
+		return entry.Result, true
+	}
+	aiCacheMisses.Add(1)
+	metrics.ObserveAICache(f.provider, f.model, false)
+	return "", false
+}
+
+// fetch asks the wrapped filter and caches a successful answer
+func (f *CachedAIFilter) fetch(content, prompt string) (string, error) {
+	result, err := f.inner.Filter(content, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	entry := &database.AICacheEntry{
+		Key:       f.key(content, prompt),
+		Provider:  f.provider,
+		Model:     f.model,
+		Result:    result,
+		CreatedAt: now,
+		ExpiresAt: now.Add(f.ttl),
+	}
+	if err := f.store.UpsertAICache(entry); err != nil {
+		log.Warnf("写入 AI 缓存失败: %v", err)
+	}
+	return result, nil
+}
+
+// IsValidResult delegates to the wrapped filter
+func (f *CachedAIFilter) IsValidResult(result string) bool {
+	return f.inner.IsValidResult(result)
+}
+
+// key hashes everything that influences the AI answer
+func (f *CachedAIFilter) key(content, prompt string) string {
+	h := sha256.New()
+	for _, part := range []string{f.provider, f.model, f.outputMode, prompt, content} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// purgeExpired removes expired entries at most once per aiCachePurgeInterval
+func (f *CachedAIFilter) purgeExpired(now time.Time) {
+	last := f.lastPurge.Load()
+	if now.UnixNano()-last < int64(aiCachePurgeInterval) || !f.lastPurge.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	n, err := f.store.DeleteExpiredAICache(now)
+	if err != nil {
+		log.Warnf("清理过期 AI 缓存失败: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Debugf("已清理 %d 条过期 AI 缓存", n)
+	}
+}
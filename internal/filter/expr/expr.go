@@ -0,0 +1,585 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Keyword Rule Expression Grammar"
+//   Timestamp: "2025-12-11T10:05:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Rules must compile once, validate in config and keep the comma/plus syntax working"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Interpreter Pattern"
+//   Quality_Check: "Legacy rules detected and compiled to the same AST; syntax errors carry positions"
+// }}
+
+// Package expr implements the keyword rule language:
+//
+//	rule    = or
+//	or      = and { ("OR" | "|" | ",") and }
+//	and     = unary { ["AND" | "&" | "+"] unary }   adjacent terms are ANDed
+//	unary   = ("NOT" | "-" | "!") unary | primary
+//	primary = "(" or ")" | compare | [field ":"] term
+//	term    = word | "quoted phrase" | /regex/
+//	compare = metric ("<" | "<=" | ">" | ">=" | "=" | "==" | "!=") number [unit]
+//
+// Words match case-insensitive substrings, quoted phrases match whole words
+// and regular expressions are case-insensitive. Terms without a field prefix
+// match the document text; fields are title, description, message, author,
+// role, domain, category, url and location.
+//
+// Comparisons test the offer plans extracted from a post, see the Metric*
+// constants. A rule with comparisons matches when it holds for at least one
+// plan; comparisons are false for posts without plans.
+//
+// Rules in which no operator other than "," and "+" stands on its own or
+// starts a word use the legacy syntax, where "a b+c,d" means ("a b" AND "c")
+// OR "d" with spaces kept as part of the keyword. Operators inside a word,
+// as in "AT&T", are part of the keyword there.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Document fields addressable with a "field:" prefix
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldMessage     = "message"
+	FieldAuthor      = "author"
+	FieldRole        = "role"
+	FieldDomain      = "domain"
+	FieldCategory    = "category"
+	FieldURL         = "url"
+	FieldLocation    = "location"
+)
+
+var knownFields = map[string]bool{
+	FieldTitle:       true,
+	FieldDescription: true,
+	FieldMessage:     true,
+	FieldAuthor:      true,
+	FieldRole:        true,
+	FieldDomain:      true,
+	FieldCategory:    true,
+	FieldURL:         true,
+	FieldLocation:    true,
+}
+
+// Document is what a rule is evaluated against
+type Document struct {
+	Text   string            // matched by terms without a field prefix
+	Fields map[string]string // matched by field-scoped terms
+	// Metrics holds one map per extracted offer plan, keyed by Metric* constants
+	Metrics []map[string]float64
+}
+
+// SyntaxError reports an invalid rule
+type SyntaxError struct {
+	Pos int // byte offset in the rule
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("关键词规则语法错误 (位置 %d): %s", e.Pos+1, e.Msg)
+}
+
+// Rule is a compiled keyword rule
+type Rule struct {
+	source       string
+	alternatives []alternative
+	numeric      bool // the rule contains comparisons
+	legacy       bool // the rule uses the legacy comma/plus syntax
+}
+
+// alternative is one top-level OR branch with its source text
+type alternative struct {
+	source string
+	node   node
+}
+
+// Compile parses a rule. An empty rule compiles to a rule that matches nothing.
+func Compile(rule string) (*Rule, error) {
+	r := &Rule{source: rule}
+	if strings.TrimSpace(rule) == "" {
+		return r, nil
+	}
+
+	if isLegacy(rule) {
+		r.alternatives = compileLegacy(rule)
+		r.legacy = true
+		return r, nil
+	}
+
+	p := &parser{lex: &lexer{src: rule}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	alternatives, err := p.parseTopLevel()
+	if err != nil {
+		return nil, err
+	}
+	r.alternatives = alternatives
+	r.numeric = p.numeric
+	return r, nil
+}
+
+// String returns the rule source
+func (r *Rule) String() string {
+	return r.source
+}
+
+// Alternatives returns the source of each top-level OR branch
+func (r *Rule) Alternatives() []string {
+	sources := make([]string, len(r.alternatives))
+	for i, alt := range r.alternatives {
+		sources[i] = alt.source
+	}
+	return sources
+}
+
+// Legacy reports whether the rule uses the legacy comma/plus syntax
+func (r *Rule) Legacy() bool {
+	return r.legacy
+}
+
+// Add returns the rule with the top-level OR branches of branch appended.
+// When the rule and the branch use different syntaxes, legacy branches are
+// rewritten in the expression syntax so that no branch changes meaning.
+func (r *Rule) Add(branch string) (*Rule, error) {
+	added, err := Compile(branch)
+	if err != nil {
+		return nil, err
+	}
+	return join(append(r.branches(), added.branches()...))
+}
+
+// Remove returns the rule without its i-th top-level OR branch
+func (r *Rule) Remove(i int) (*Rule, error) {
+	if i < 0 || i >= len(r.alternatives) {
+		return nil, fmt.Errorf("规则序号 %d 超出范围", i+1)
+	}
+	branches := r.branches()
+	return join(append(branches[:i:i], branches[i+1:]...))
+}
+
+// branch is the source of a top-level OR branch and the syntax it is written in
+type branch struct {
+	source string
+	legacy bool
+}
+
+// branches returns the top-level OR branches of the rule
+func (r *Rule) branches() []branch {
+	branches := make([]branch, len(r.alternatives))
+	for i, alt := range r.alternatives {
+		branches[i] = branch{source: alt.source, legacy: r.legacy}
+	}
+	return branches
+}
+
+// join compiles branches into one rule, each keeping the meaning it has in
+// its own syntax. Unless all branches are legacy, legacy branches are
+// rewritten in the expression syntax; an expression rule that would read as
+// legacy, such as "vps kvm", gets its first branch parenthesized.
+func join(branches []branch) (*Rule, error) {
+	legacy := true
+	for _, b := range branches {
+		legacy = legacy && b.legacy
+	}
+
+	sources := make([]string, len(branches))
+	for i, b := range branches {
+		sources[i] = b.source
+		if b.legacy && !legacy {
+			sources[i] = legacyToExpression(b.source)
+		}
+	}
+	if !legacy && isLegacy(strings.Join(sources, ", ")) {
+		sources[0] = "(" + sources[0] + ")"
+	}
+	return Compile(strings.Join(sources, ", "))
+}
+
+// legacyToExpression rewrites a legacy branch "a b+c" in the expression
+// syntax. Keywords that are not a single plain word become regular
+// expressions of the literal keyword, which keeps the substring match: a
+// quoted phrase would only match whole words.
+func legacyToExpression(group string) string {
+	var terms []string
+	for _, keyword := range strings.Split(group, "+") {
+		keyword = strings.TrimSpace(keyword)
+		switch {
+		case keyword == "":
+		case isPlainWord(keyword):
+			terms = append(terms, keyword)
+		default:
+			terms = append(terms, "/"+strings.ReplaceAll(regexp.QuoteMeta(keyword), "/", `\/`)+"/")
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// isPlainWord reports whether keyword lexes as a single word term without a field prefix
+func isPlainWord(keyword string) bool {
+	l := &lexer{src: keyword}
+	tok, err := l.next()
+	if err != nil || tok.kind != tokTerm || tok.term != termWord || tok.field != "" || tok.value != keyword {
+		return false
+	}
+	tok, err = l.next()
+	return err == nil && tok.kind == tokEOF
+}
+
+// Match evaluates the rule and returns the source of the top-level OR
+// branch that matched
+func (r *Rule) Match(doc Document) (string, bool) {
+	plans := []map[string]float64{nil}
+	if r.numeric && len(doc.Metrics) > 0 {
+		plans = doc.Metrics
+	}
+
+	for _, alt := range r.alternatives {
+		for _, plan := range plans {
+			if alt.node.eval(&doc, plan) {
+				return alt.source, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isLegacy reports whether a rule only uses the old comma/plus syntax. Only
+// operators that stand on their own, or start a word like "-nat", switch a
+// rule to the expression grammar, so that legacy keywords such as "AT&T" or
+// "$5/mo" keep their meaning.
+func isLegacy(rule string) bool {
+	for _, group := range strings.Split(rule, ",") {
+		for _, keyword := range strings.Split(group, "+") {
+			for _, word := range strings.Fields(keyword) {
+				if isOperatorWord(word) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// isOperatorWord reports whether a word of a rule is, or starts with, a
+// construct of the expression grammar
+func isOperatorWord(word string) bool {
+	switch word {
+	case "AND", "OR", "NOT", "&", "|":
+		return true
+	}
+	if strings.ContainsRune(`-!("/<>=`, rune(word[0])) || strings.HasSuffix(word, ")") {
+		return true
+	}
+	if i := strings.IndexByte(word, ':'); i > 0 && knownFields[strings.ToLower(word[:i])] {
+		return true
+	}
+
+	// Comparisons written without spaces, e.g. ram>=1GB
+	if i := strings.IndexAny(word, "<>=!"); i > 0 {
+		_, ok := metricKind[strings.ToLower(word[:i])]
+		return ok
+	}
+	return false
+}
+
+// compileLegacy compiles "a+b,c" into (a AND b) OR c. Empty keywords and
+// groups are skipped instead of matching everything.
+func compileLegacy(rule string) []alternative {
+	var alternatives []alternative
+	for _, group := range strings.Split(rule, ",") {
+		var terms andNode
+		for _, keyword := range strings.Split(group, "+") {
+			keyword = strings.TrimSpace(keyword)
+			if keyword == "" {
+				continue
+			}
+			terms = append(terms, newWordTerm("", keyword))
+		}
+		if len(terms) == 0 {
+			continue
+		}
+		alternatives = append(alternatives, alternative{source: strings.TrimSpace(group), node: terms})
+	}
+	return alternatives
+}
+
+// parser builds the expression tree from tokens
+type parser struct {
+	lex     *lexer
+	tok     token
+	prev    token
+	numeric bool
+}
+
+// advance moves to the next token
+func (p *parser) advance() error {
+	p.prev = p.tok
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// parseTopLevel parses the whole rule, keeping each top-level OR branch separate
+func (p *parser) parseTopLevel() ([]alternative, error) {
+	var alternatives []alternative
+	for {
+		start := p.tok.pos
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, alternative{
+			source: strings.TrimSpace(p.lex.src[start:p.prev.end]),
+			node:   n,
+		})
+
+		switch p.tok.kind {
+		case tokEOF:
+			return alternatives, nil
+		case tokOr:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+// parseOr parses a parenthesized OR expression
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := orNode{left}
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+
+	if len(nodes) == 1 {
+		return left, nil
+	}
+	return nodes, nil
+}
+
+// parseAnd parses explicit and implicit conjunctions
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := andNode{left}
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case tokNot, tokLParen, tokTerm:
+			// Adjacent terms are ANDed
+		default:
+			if len(nodes) == 1 {
+				return left, nil
+			}
+			return nodes, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+}
+
+// parseUnary parses negations
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return notNode{operand}, nil
+}
+
+// parsePrimary parses a term or a parenthesized expression
+func (p *parser) parsePrimary() (node, error) {
+	switch p.tok.kind {
+	case tokLParen:
+		open := p.tok.pos
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, &SyntaxError{Pos: open, Msg: "括号未闭合"}
+		}
+		return inner, p.advance()
+	case tokTerm:
+		term, err := newTerm(p.tok)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.term == termCompare {
+			p.numeric = true
+		}
+		return term, p.advance()
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+// unexpected reports the current token as out of place
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return &SyntaxError{Pos: p.tok.pos, Msg: "规则意外结束"}
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf("意外的 %q", p.lex.src[p.tok.pos:p.tok.end])}
+}
+
+// node is an expression tree node
+type node interface {
+	eval(doc *Document, plan map[string]float64) bool
+}
+
+type orNode []node
+
+func (n orNode) eval(doc *Document, plan map[string]float64) bool {
+	for _, child := range n {
+		if child.eval(doc, plan) {
+			return true
+		}
+	}
+	return false
+}
+
+type andNode []node
+
+func (n andNode) eval(doc *Document, plan map[string]float64) bool {
+	for _, child := range n {
+		if !child.eval(doc, plan) {
+			return false
+		}
+	}
+	return true
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(doc *Document, plan map[string]float64) bool {
+	return !n.operand.eval(doc, plan)
+}
+
+// termNode matches a single word, phrase or regex against one field
+type termNode struct {
+	field string
+	match func(text string) bool
+}
+
+func (n termNode) eval(doc *Document, _ map[string]float64) bool {
+	if n.field == "" {
+		return n.match(doc.Text)
+	}
+	return n.match(doc.Fields[n.field])
+}
+
+// newTerm compiles a term token
+func newTerm(tok token) (node, error) {
+	switch tok.term {
+	case termPhrase:
+		words := strings.Fields(tok.value)
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		// Whole words only: the phrase may not be preceded or followed by a letter, digit or underscore
+		// QuoteMeta output still fails to compile when the phrase is not valid UTF-8
+		re, err := regexp.Compile(`(?i)(?:^|[^\pL\pN_])` + strings.Join(words, `\s+`) + `(?:$|[^\pL\pN_])`)
+		if err != nil {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("无效的短语 %q: %v", tok.value, err)}
+		}
+		return termNode{field: tok.field, match: re.MatchString}, nil
+	case termRegex:
+		re, err := regexp.Compile("(?i)" + tok.value)
+		if err != nil {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("无效的正则表达式 /%s/: %v", tok.value, err)}
+		}
+		return termNode{field: tok.field, match: re.MatchString}, nil
+	case termCompare:
+		value, ok := normalizeValue(tok.value, tok.num, tok.unit)
+		if !ok {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("%s 不支持单位 %q", tok.value, tok.unit)}
+		}
+		return compareNode{metric: tok.value, op: tok.op, value: value}, nil
+	default:
+		return newWordTerm(tok.field, tok.value), nil
+	}
+}
+
+// newWordTerm matches a case-insensitive substring
+func newWordTerm(field, word string) termNode {
+	word = strings.ToLower(word)
+	return termNode{field: field, match: func(text string) bool {
+		return strings.Contains(strings.ToLower(text), word)
+	}}
+}
+
+// compareNode compares an offer plan metric with a value in the metric's base unit
+type compareNode struct {
+	metric string
+	op     string
+	value  float64
+}
+
+func (n compareNode) eval(_ *Document, plan map[string]float64) bool {
+	v, ok := plan[n.metric]
+	if !ok {
+		return false
+	}
+
+	// Tolerate rounding from unit and currency conversions
+	const epsilon = 1e-6
+	switch n.op {
+	case "<":
+		return v < n.value-epsilon
+	case "<=":
+		return v <= n.value+epsilon
+	case ">":
+		return v > n.value+epsilon
+	case ">=":
+		return v >= n.value-epsilon
+	case "!=":
+		return math.Abs(v-n.value) > epsilon
+	default: // "=" and "=="
+		return math.Abs(v-n.value) <= epsilon
+	}
+}
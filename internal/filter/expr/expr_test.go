@@ -0,0 +1,329 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompileLegacyDetection(t *testing.T) {
+	tests := []struct {
+		rule   string
+		legacy bool
+	}{
+		{"giveaway,sale+vps", true},
+		{"black friday+vps,restock", true},
+		{"AT&T+vps", true},
+		{"Q&A", true},
+		{"$5/mo", true},
+		{"kvm|xen", true},
+		{"sale!", true},
+		{"ram", true},
+		{"vps AND kvm", false},
+		{"vps & kvm", false},
+		{"vps | dedicated", false},
+		{"NOT nat", false},
+		{"vps -nat", false},
+		{"vps !nat", false},
+		{"(kvm OR xen)", false},
+		{`"black friday"`, false},
+		{`/\d+gb/`, false},
+		{"title:offer", false},
+		{"ram >= 1GB", false},
+		{"ram>=1GB", false},
+	}
+
+	for _, tt := range tests {
+		if got := isLegacy(tt.rule); got != tt.legacy {
+			t.Errorf("isLegacy(%q) = %v, want %v", tt.rule, got, tt.legacy)
+		}
+		if _, err := Compile(tt.rule); err != nil {
+			t.Errorf("Compile(%q) error: %v", tt.rule, err)
+		}
+	}
+}
+
+func TestMatchText(t *testing.T) {
+	tests := []struct {
+		rule  string
+		text  string
+		match bool
+	}{
+		// Legacy syntax: spaces are part of the keyword
+		{"black friday+vps,restock", "Black Friday VPS deal", true},
+		{"black friday+vps,restock", "Friday deal: black vps now", false},
+		{"black friday+vps,restock", "Restocked!", true},
+		{"AT&T+vps", "AT&T VPS offer", true},
+		{"AT&T+vps", "AT and T vps", false},
+		{"$5/mo", "KVM for $5/mo", true},
+		{",,", "anything", false},
+
+		// Expression grammar
+		{"vps AND kvm", "KVM VPS", true},
+		{"vps kvm", "KVM VPS", false}, // legacy: one keyword "vps kvm"
+		{"vps & kvm", "vps only", false},
+		{"vps | dedicated", "Dedicated server", true},
+		{"vps OR dedicated", "shared hosting", false},
+
+		// NOT
+		{"vps -nat", "NAT VPS", false},
+		{"vps -nat", "KVM VPS", true},
+		{"vps NOT nat", "KVM VPS", true},
+		{"vps !nat", "nat vps", false},
+		{"NOT NOT vps", "vps", true},
+
+		// Precedence: NOT > AND > OR
+		{"a OR b AND c", "a", true},
+		{"a OR b AND c", "b", false},
+		{"a OR b AND c", "b c", true},
+		{"(kvm OR xen) -openvz", "xen", true},
+		{"(kvm OR xen) -openvz", "kvm openvz", false},
+		{"(kvm OR xen) -openvz", "openvz", false},
+		{"-a b OR c", "b", true},
+		{"-a b OR c", "a b", false},
+		{"-a b OR c", "a c", true},
+
+		// Phrases match whole words, regular expressions ignore case
+		{`"nat"`, "international", false},
+		{`"nat"`, "NAT only", true},
+		{`"black friday"`, "black   Friday sale", true},
+		{`/\d+\s*gb ram/`, "2 GB RAM", true},
+		{`/\d+\s*gb ram/`, "lots of ram", false},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", tt.rule, err)
+		}
+		if _, got := rule.Match(Document{Text: tt.text}); got != tt.match {
+			t.Errorf("%q matching %q = %v, want %v", tt.rule, tt.text, got, tt.match)
+		}
+	}
+}
+
+func TestMatchFields(t *testing.T) {
+	doc := Document{
+		Text: "Restocked the 1GB plan",
+		Fields: map[string]string{
+			FieldTitle:  "Black Friday VPS Offers",
+			FieldAuthor: "bob",
+			FieldDomain: "lowendtalk.com",
+		},
+	}
+
+	tests := []struct {
+		rule  string
+		match bool
+	}{
+		{`title:"black friday"`, true},
+		{`title:restocked`, false},
+		{`restocked`, true},
+		{`author:bob AND domain:lowendtalk`, true},
+		{`author:alice OR title:/offers?$/`, true},
+		{`TITLE:vps -author:bob`, false},
+		{`message:restocked`, false},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", tt.rule, err)
+		}
+		if _, got := rule.Match(doc); got != tt.match {
+			t.Errorf("%q = %v, want %v", tt.rule, got, tt.match)
+		}
+	}
+}
+
+func TestMatchComparisons(t *testing.T) {
+	doc := Document{
+		Text: "KVM offers",
+		Metrics: []map[string]float64{
+			{MetricPrice: 10, MetricPricePerYear: 10, MetricRAM: 0.5},
+			{MetricPrice: 18, MetricPricePerYear: 18, MetricRAM: 2, MetricCPU: 2},
+		},
+	}
+
+	tests := []struct {
+		rule  string
+		match bool
+	}{
+		{"price_per_year <= 15 USD AND ram >= 1GB", false}, // no single plan satisfies both
+		{"price_per_year <= 18 AND ram >= 1GB", true},
+		{"ram >= 512MB", true},
+		{"ram>=2048MB", true},
+		{"price < $10", false},
+		{"price <= $10", true},
+		{"price_per_year < 10 EUR", true}, // 10 EUR is 10.8 USD
+		{"cpu = 2", true},
+		{"cpu != 2", false}, // a plan without cpu fails every comparison
+		{"disk >= 1", false},
+		{"price_per_month < 5", false}, // no billing period extracted
+		{"kvm price < 12", true},
+		{"(price < 5 OR cpu >= 2) kvm", true},
+		{"bandwidth >= 1TB OR -kvm", false},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", tt.rule, err)
+		}
+		if _, got := rule.Match(doc); got != tt.match {
+			t.Errorf("%q = %v, want %v", tt.rule, got, tt.match)
+		}
+	}
+
+	rule, _ := Compile("price < 100")
+	if _, ok := rule.Match(Document{Text: "no offer"}); ok {
+		t.Error("comparison matched a document without plans")
+	}
+}
+
+func TestMatchReturnsBranch(t *testing.T) {
+	tests := []struct {
+		rule   string
+		text   string
+		branch string
+	}{
+		{"giveaway, sale+vps", "VPS sale", "sale+vps"},
+		{"kvm AND -openvz | xen", "xen openvz", "xen"},
+		{"(a OR b) c, d", "b c", "(a OR b) c"},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", tt.rule, err)
+		}
+		if branch, _ := rule.Match(Document{Text: tt.text}); branch != tt.branch {
+			t.Errorf("%q matching %q returned branch %q, want %q", tt.rule, tt.text, branch, tt.branch)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, rule := range []string{
+		"(kvm OR xen",
+		`"black friday`,
+		"/kvm",
+		"/(/",
+		"vps AND",
+		"OR vps",
+		"ram >= lots",
+		"cpu >= 2GB",
+		"title:",
+		"\"0\xc8\"",
+	} {
+		_, err := Compile(rule)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Compile(%q) error = %v, want a SyntaxError", rule, err)
+		}
+	}
+}
+
+func TestAddRemoveKeepsMeaning(t *testing.T) {
+	texts := []string{
+		"Friday deal: black vps now",
+		"Black Friday VPS deal",
+		"Restocked!",
+		"KVM without openvz",
+		"KVM on OpenVZ",
+		"AT&T vps",
+		"AT T vps",
+		"$5/mo kvm",
+		"vps kvm",
+		"kvm and vps",
+	}
+	matches := func(rule *Rule) []bool {
+		result := make([]bool, len(texts))
+		for i, text := range texts {
+			_, result[i] = rule.Match(Document{Text: text})
+		}
+		return result
+	}
+	matchesAny := func(rules ...*Rule) []bool {
+		result := make([]bool, len(texts))
+		for _, rule := range rules {
+			for i, ok := range matches(rule) {
+				result[i] = result[i] || ok
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		rule   string
+		branch string
+	}{
+		{"black friday+vps,restock", "kvm AND -openvz"},
+		{"AT&T+vps, $5/mo", "kvm -openvz"},
+		{"kvm AND -openvz", "black friday+vps"},
+		{"vps kvm", "restock"},
+		{"kvm vps | -openvz", "AT&T"},
+		{"", "kvm AND -openvz"},
+	}
+
+	for _, tt := range tests {
+		rule, err := Compile(tt.rule)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %v", tt.rule, err)
+		}
+		branch, _ := Compile(tt.branch)
+
+		added, err := rule.Add(tt.branch)
+		if err != nil {
+			t.Fatalf("%q Add(%q) error: %v", tt.rule, tt.branch, err)
+		}
+		if got, want := matches(added), matchesAny(rule, branch); !equalBools(got, want) {
+			t.Errorf("%q Add(%q) = %q matches %v, want %v", tt.rule, tt.branch, added, got, want)
+		}
+
+		removed, err := added.Remove(len(added.Alternatives()) - 1)
+		if err != nil {
+			t.Fatalf("%q Remove error: %v", added, err)
+		}
+		if got, want := matches(removed), matches(rule); !equalBools(got, want) {
+			t.Errorf("%q Remove(last) = %q matches %v, want %v", added, removed, got, want)
+		}
+		if got, want := len(removed.Alternatives()), len(rule.Alternatives()); got != want {
+			t.Errorf("%q Remove(last) left %d branches, want %d", added, got, want)
+		}
+	}
+}
+
+func TestAddRewritesLegacyBranches(t *testing.T) {
+	rule, _ := Compile("black friday+vps,restock,$5/mo")
+	added, err := rule.Add("kvm AND -openvz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/black friday/ vps", "restock", "$5/mo", "kvm AND -openvz"}
+	got := added.Alternatives()
+	if len(got) != len(want) {
+		t.Fatalf("Alternatives() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Alternatives()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if added.Legacy() {
+		t.Error("rule with an expression branch is legacy")
+	}
+
+	if _, err := rule.Remove(3); err == nil {
+		t.Error("Remove out of range succeeded")
+	}
+}
+
+func equalBools(a, b []bool) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
@@ -0,0 +1,289 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Keyword Rule Expression Grammar"
+//   Timestamp: "2025-12-11T10:05:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Comma/plus rules could not express exclusions, phrases, regex or field scoping"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Recursive Descent"
+//   Quality_Check: "Tokens carry source positions for syntax error reporting"
+// }}
+
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokTerm
+)
+
+// termKind is how a term matches text
+type termKind int
+
+const (
+	termWord    termKind = iota // case-insensitive substring
+	termPhrase                  // quoted, whole words only
+	termRegex                   // /pattern/, case-insensitive
+	termCompare                 // metric op number [unit], e.g. ram >= 1GB
+)
+
+// token is a lexical unit of a rule
+type token struct {
+	kind  tokenKind
+	pos   int // byte offset in the rule
+	end   int // byte offset just past the token
+	field string
+	term  termKind
+	value string  // word, phrase, pattern or metric name
+	op    string  // comparison operator
+	num   float64 // comparison value
+	unit  string  // comparison unit, empty for the metric's base unit
+}
+
+// lexer splits a rule into tokens
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos, end: l.pos}, nil
+	}
+
+	start := l.pos
+	simple := func(kind tokenKind) (token, error) {
+		l.pos++
+		return token{kind: kind, pos: start, end: l.pos}, nil
+	}
+
+	switch c := l.src[l.pos]; c {
+	case '(':
+		return simple(tokLParen)
+	case ')':
+		return simple(tokRParen)
+	case ',', '|':
+		return simple(tokOr)
+	case '+', '&':
+		return simple(tokAnd)
+	case '-', '!':
+		return simple(tokNot)
+	}
+
+	if tok, ok, err := l.comparison(); ok || err != nil {
+		tok.pos = start
+		return tok, err
+	}
+
+	field := l.fieldPrefix()
+	tok, err := l.term()
+	if err != nil {
+		return tok, err
+	}
+	tok.pos = start
+	tok.field = field
+
+	if field == "" && tok.term == termWord {
+		switch tok.value {
+		case "AND":
+			tok.kind = tokAnd
+		case "OR":
+			tok.kind = tokOr
+		case "NOT":
+			tok.kind = tokNot
+		}
+	}
+	return tok, nil
+}
+
+// fieldPrefix consumes a known "field:" prefix and returns the field name
+func (l *lexer) fieldPrefix() string {
+	i := l.pos
+	for i < len(l.src) && isLetter(l.src[i]) {
+		i++
+	}
+	if i == l.pos || i >= len(l.src) || l.src[i] != ':' {
+		return ""
+	}
+
+	name := strings.ToLower(l.src[l.pos:i])
+	if !knownFields[name] {
+		return ""
+	}
+	l.pos = i + 1
+	return name
+}
+
+// comparison lexes "metric op number [unit]". It reports false without
+// consuming anything when the input is not a comparison, so that a metric
+// name on its own is still matched as a word.
+func (l *lexer) comparison() (token, bool, error) {
+	i := l.pos
+	for i < len(l.src) && (isLetter(l.src[i]) || l.src[i] == '_') {
+		i++
+	}
+	metric := strings.ToLower(l.src[l.pos:i])
+	if _, ok := metricKind[metric]; !ok {
+		return token{}, false, nil
+	}
+
+	j := l.skipSpaces(i)
+	op := ""
+	for _, candidate := range []string{"<=", ">=", "!=", "==", "<", ">", "="} {
+		if strings.HasPrefix(l.src[j:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return token{}, false, nil
+	}
+	l.pos = l.skipSpaces(j + len(op))
+
+	// A currency symbol may precede the number, e.g. price <= $5
+	unit := l.symbol()
+	l.pos = l.skipSpaces(l.pos)
+
+	numStart := l.pos
+	for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+		l.pos++
+	}
+	num, err := strconv.ParseFloat(l.src[numStart:l.pos], 64)
+	if err != nil {
+		return token{}, true, &SyntaxError{Pos: numStart, Msg: "比较运算缺少有效的数值"}
+	}
+
+	if unit == "" {
+		unit = l.unit(metric)
+	}
+	return token{kind: tokTerm, end: l.pos, term: termCompare, value: metric, op: op, num: num, unit: unit}, true, nil
+}
+
+// symbol consumes a leading currency symbol such as "$" or "€"
+func (l *lexer) symbol() string {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	if !strings.ContainsRune("$€£¥₹", r) {
+		return ""
+	}
+	l.pos += size
+	return string(r)
+}
+
+// unit consumes the unit after a comparison value. A unit attached to the
+// number is always consumed so that unknown units are reported; a unit
+// separated by spaces is only consumed when it is valid for the metric.
+func (l *lexer) unit(metric string) string {
+	attached := l.pos < len(l.src) && !isSpace(l.src[l.pos])
+	i := l.skipSpaces(l.pos)
+	j := i
+	for j < len(l.src) && !isSpace(l.src[j]) && !strings.ContainsRune(`()",|+&`, rune(l.src[j])) {
+		j++
+	}
+	unit := l.src[i:j]
+	if unit == "" {
+		return ""
+	}
+	if _, ok := normalizeValue(metric, 1, unit); !ok && !attached {
+		return ""
+	}
+	l.pos = j
+	return unit
+}
+
+// skipSpaces returns the offset of the first non-space byte at or after i
+func (l *lexer) skipSpaces(i int) int {
+	for i < len(l.src) && isSpace(l.src[i]) {
+		i++
+	}
+	return i
+}
+
+// term lexes a quoted phrase, a regex literal or a bare word
+func (l *lexer) term() (token, error) {
+	start := l.pos
+	if l.pos >= len(l.src) || isSpace(l.src[l.pos]) {
+		return token{}, &SyntaxError{Pos: start, Msg: "字段前缀后缺少匹配内容"}
+	}
+
+	switch l.src[l.pos] {
+	case '"':
+		value, err := l.delimited('"')
+		if err != nil {
+			return token{}, err
+		}
+		if strings.TrimSpace(value) == "" {
+			return token{}, &SyntaxError{Pos: start, Msg: "短语不能为空"}
+		}
+		return token{kind: tokTerm, end: l.pos, term: termPhrase, value: value}, nil
+	case '/':
+		value, err := l.delimited('/')
+		if err != nil {
+			return token{}, err
+		}
+		if value == "" {
+			return token{}, &SyntaxError{Pos: start, Msg: "正则表达式不能为空"}
+		}
+		return token{kind: tokTerm, end: l.pos, term: termRegex, value: value}, nil
+	}
+
+	for l.pos < len(l.src) && !isSpace(l.src[l.pos]) && !strings.ContainsRune(`()",|+&`, rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos == start {
+		return token{}, &SyntaxError{Pos: start, Msg: "字段前缀后缺少匹配内容"}
+	}
+	return token{kind: tokTerm, end: l.pos, term: termWord, value: l.src[start:l.pos]}, nil
+}
+
+// delimited reads text up to the closing delimiter; a backslash escapes the delimiter
+func (l *lexer) delimited(delim byte) (string, error) {
+	start := l.pos
+	l.pos++ // opening delimiter
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == delim:
+			b.WriteByte(delim)
+			l.pos += 2
+		case c == delim:
+			l.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	if delim == '"' {
+		return "", &SyntaxError{Pos: start, Msg: "引号未闭合"}
+	}
+	return "", &SyntaxError{Pos: start, Msg: "正则表达式缺少结尾的 /"}
+}
+
+// isLetter reports whether a byte is an ASCII letter
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isSpace reports whether an ASCII byte is whitespace
+func isSpace(c byte) bool {
+	return c < 0x80 && unicode.IsSpace(rune(c))
+}
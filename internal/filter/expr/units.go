@@ -0,0 +1,113 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Offer Numeric Threshold Rules"
+//   Timestamp: "2025-12-12T09:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Price and spec thresholds need unit-aware comparisons shared with the extractor"
+//   Principle_Applied: "Aether-Engineering-DRY"
+//   Quality_Check: "Every metric has one base unit; rule and extractor values normalize identically"
+// }}
+
+package expr
+
+import "strings"
+
+// Offer metrics comparable in rules, e.g. "price_per_year <= 15 USD AND ram >= 1GB".
+// Prices are in USD, sizes in GB, cpu is a core count.
+const (
+	MetricPrice         = "price"
+	MetricPricePerMonth = "price_per_month"
+	MetricPricePerYear  = "price_per_year"
+	MetricRAM           = "ram"
+	MetricDisk          = "disk"
+	MetricBandwidth     = "bandwidth"
+	MetricCPU           = "cpu"
+)
+
+// UnmeteredBandwidth is the bandwidth metric of an unmetered plan, in GB
+const UnmeteredBandwidth = 1e9
+
+// CurrencyToUSD holds approximate exchange rates used to compare prices
+// across currencies. They are deliberately static: thresholds only need to
+// be roughly right and the monitor must not depend on a rates API.
+var CurrencyToUSD = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"CAD": 0.73,
+	"AUD": 0.66,
+	"CNY": 0.14,
+	"JPY": 0.0067,
+	"INR": 0.012,
+}
+
+// currencyAliases maps symbols and names to currency codes
+var currencyAliases = map[string]string{
+	"$":   "USD",
+	"us$": "USD",
+	"€":   "EUR",
+	"£":   "GBP",
+	"c$":  "CAD",
+	"a$":  "AUD",
+	"¥":   "CNY",
+	"rmb": "CNY",
+	"₹":   "INR",
+}
+
+// CurrencyCode normalizes a currency symbol or code, e.g. "$" or "usd" to "USD".
+// It returns an empty string for unknown currencies.
+func CurrencyCode(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if code, ok := currencyAliases[s]; ok {
+		return code
+	}
+	if _, ok := CurrencyToUSD[strings.ToUpper(s)]; ok {
+		return strings.ToUpper(s)
+	}
+	return ""
+}
+
+// sizeUnits converts size units to GB
+var sizeUnits = map[string]float64{
+	"mb": 1.0 / 1024, "m": 1.0 / 1024, "mib": 1.0 / 1024,
+	"gb": 1, "g": 1, "gib": 1,
+	"tb": 1024, "t": 1024, "tib": 1024,
+	"pb": 1024 * 1024,
+}
+
+// SizeToGB converts a size with a unit such as "MB" or "TB" to GB
+func SizeToGB(value float64, unit string) (float64, bool) {
+	factor, ok := sizeUnits[strings.ToLower(unit)]
+	return value * factor, ok
+}
+
+// metricKind groups metrics by unit family
+var metricKind = map[string]string{
+	MetricPrice:         "currency",
+	MetricPricePerMonth: "currency",
+	MetricPricePerYear:  "currency",
+	MetricRAM:           "size",
+	MetricDisk:          "size",
+	MetricBandwidth:     "size",
+	MetricCPU:           "count",
+}
+
+// normalizeValue converts a rule value with an optional unit to the metric's base unit
+func normalizeValue(metric string, value float64, unit string) (float64, bool) {
+	if unit == "" {
+		return value, true
+	}
+
+	switch metricKind[metric] {
+	case "currency":
+		code := CurrencyCode(unit)
+		if code == "" {
+			return 0, false
+		}
+		return value * CurrencyToUSD[code], true
+	case "size":
+		return SizeToGB(value, unit)
+	default:
+		return 0, false
+	}
+}
@@ -11,7 +11,11 @@
 package filter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/imhuimie/let-monitor-go/internal/config"
 )
@@ -22,18 +26,71 @@ type AIFilterInterface interface {
 	IsValidResult(result string) bool
 }
 
-// NewAIFilterFromConfig creates an AI filter based on configuration
+// NewAIFilterFromConfig creates an AI filter trying the configured providers in order
 func NewAIFilterFromConfig(cfg *config.Config) (AIFilterInterface, error) {
+	return NewCachedAIFilterFromConfig(cfg, nil, nil, nil)
+}
+
+// NewCachedAIFilterFromConfig creates an AI filter trying the configured
+// providers in order. When ai_cache_ttl is set, each provider's results are
+// cached in store. Token usage is recorded in usage unless it is nil, and
+// circuit breakers are kept in breakers unless it is nil.
+func NewCachedAIFilterFromConfig(cfg *config.Config, store AICacheStore, usage *UsageTracker, breakers *CircuitBreakers) (AIFilterInterface, error) {
 	if !cfg.UseAIFilter {
 		return nil, fmt.Errorf("AI 过滤未启用")
 	}
 
-	switch cfg.AIProvider {
+	chain := NewChainAIFilter(breakers, cfg.AIBreakerThreshold, time.Duration(cfg.AIBreakerCooldown)*time.Second)
+	for _, provider := range cfg.AIProviderChain() {
+		aiFilter, err := NewProviderFilter(cfg, provider)
+		if err != nil {
+			return nil, err
+		}
+		model := cfg.AIModel(provider)
+		if usage != nil {
+			aiFilter = NewMeteredAIFilter(aiFilter, usage, provider, model, cfg.AIPricing[model])
+		}
+		if cfg.AICacheTTL > 0 && store != nil {
+			ttl := time.Duration(cfg.AICacheTTL) * time.Hour
+			aiFilter = NewCachedAIFilter(aiFilter, store, provider, model, cfg.AIOutputMode, ttl)
+		}
+		chain.Add(provider, providerSettings(cfg, provider), aiFilter)
+	}
+	return chain, nil
+}
+
+// providerSettings hashes the settings NewProviderFilter uses for provider,
+// so that a circuit breaker is reset only when they change
+func providerSettings(cfg *config.Config, provider string) string {
+	var parts []string
+	switch provider {
+	case "cloudflare":
+		parts = []string{cfg.CFAccountID, cfg.CFToken, cfg.Model}
+	case "openai":
+		parts = []string{cfg.OpenAIAPIURL, cfg.OpenAIAPIKey, cfg.OpenAIModel}
+	case "anthropic":
+		parts = []string{cfg.AnthropicAPIURL, cfg.AnthropicAPIKey, cfg.AnthropicModel, fmt.Sprint(cfg.AnthropicMaxTokens)}
+	case "ollama":
+		parts = []string{cfg.OllamaURL, cfg.OllamaModel}
+	}
+	parts = append(parts, cfg.AIOutputMode)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewProviderFilter creates the AI filter of a single provider
+func NewProviderFilter(cfg *config.Config, provider string) (AIFilterInterface, error) {
+	switch provider {
 	case "cloudflare":
-		return NewAIFilter(cfg.CFAccountID, cfg.CFToken, cfg.Model), nil
+		return NewAIFilter(cfg.CFAccountID, cfg.CFToken, cfg.Model, cfg.AIOutputMode), nil
 	case "openai":
-		return NewOpenAIFilter(cfg.OpenAIAPIURL, cfg.OpenAIAPIKey, cfg.OpenAIModel), nil
+		return NewOpenAIFilter(cfg.OpenAIAPIURL, cfg.OpenAIAPIKey, cfg.OpenAIModel, cfg.AIOutputMode), nil
+	case "anthropic":
+		return NewAnthropicFilter(cfg.AnthropicAPIURL, cfg.AnthropicAPIKey, cfg.AnthropicModel, cfg.AnthropicMaxTokens, cfg.AIOutputMode), nil
+	case "ollama":
+		return NewOllamaFilter(cfg.OllamaURL, cfg.OllamaModel, cfg.AIOutputMode), nil
 	default:
-		return nil, fmt.Errorf("不支持的 AI 提供商: %s", cfg.AIProvider)
+		return nil, fmt.Errorf("不支持的 AI 提供商: %s", provider)
 	}
 }
@@ -12,45 +12,89 @@ package filter
 
 import (
 	"strings"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter/expr"
 )
 
 // KeywordFilter filters content based on keyword rules
 type KeywordFilter struct {
-	rule string
+	rule *expr.Rule
 }
 
-// NewKeywordFilter creates a new keyword filter
-func NewKeywordFilter(rule string) *KeywordFilter {
-	return &KeywordFilter{rule: rule}
+// NewKeywordFilter compiles a keyword rule, see package expr for the syntax.
+// The legacy format "keyword1+keyword2,keyword3" means (keyword1 AND keyword2) OR keyword3.
+func NewKeywordFilter(rule string) (*KeywordFilter, error) {
+	compiled, err := expr.Compile(rule)
+	if err != nil {
+		return nil, err
+	}
+	return &KeywordFilter{rule: compiled}, nil
 }
 
-// Match checks if text matches the keyword rule
-// Rule format: "keyword1+keyword2,keyword3" means (keyword1 AND keyword2) OR keyword3
+// Match checks if text matches the keyword rule; field-scoped terms see empty fields
 func (f *KeywordFilter) Match(text string) bool {
-	if f.rule == "" {
-		return false
+	_, ok := f.rule.Match(expr.Document{Text: text})
+	return ok
+}
+
+// MatchDocument returns the top-level branch of the rule that matches doc
+func (f *KeywordFilter) MatchDocument(doc expr.Document) (string, bool) {
+	return f.rule.Match(doc)
+}
+
+// ThreadDocument exposes a thread to keyword rules. Unscoped terms match the
+// title and description; comparisons use the offer extracted from the thread.
+func ThreadDocument(thread *database.Thread) expr.Document {
+	offer := thread.Offer
+	if offer == nil {
+		offer = ExtractOffer(thread.Title + "\n" + thread.Description)
 	}
 
-	text = strings.ToLower(text)
-	orGroups := strings.Split(f.rule, ",")
-
-	for _, group := range orGroups {
-		group = strings.TrimSpace(group)
-		andKeywords := strings.Split(group, "+")
-
-		allMatch := true
-		for _, keyword := range andKeywords {
-			keyword = strings.TrimSpace(strings.ToLower(keyword))
-			if !strings.Contains(text, keyword) {
-				allMatch = false
-				break
-			}
-		}
-
-		if allMatch {
-			return true
-		}
+	return expr.Document{
+		Text: thread.Title + "\n" + thread.Description,
+		Fields: map[string]string{
+			expr.FieldTitle:       thread.Title,
+			expr.FieldDescription: thread.Description,
+			expr.FieldAuthor:      thread.Creator,
+			expr.FieldDomain:      thread.Domain,
+			expr.FieldCategory:    thread.Category,
+			expr.FieldURL:         thread.Link,
+			expr.FieldLocation:    offerLocations(offer),
+		},
+		Metrics: OfferMetrics(offer),
 	}
+}
 
-	return false
+// CommentDocument exposes a comment to keyword rules. Unscoped terms match the
+// message; title, domain and category come from the thread. Comparisons use
+// the offer in the message, falling back to the thread's offer.
+func CommentDocument(thread *database.Thread, comment *database.Comment) expr.Document {
+	offer := ExtractOffer(comment.Message)
+	if offer == nil {
+		offer = thread.Offer
+	}
+
+	return expr.Document{
+		Text: comment.Message,
+		Fields: map[string]string{
+			expr.FieldTitle:    thread.Title,
+			expr.FieldMessage:  comment.Message,
+			expr.FieldAuthor:   comment.Author,
+			expr.FieldRole:     comment.Role,
+			expr.FieldDomain:   thread.Domain,
+			expr.FieldCategory: thread.Category,
+			expr.FieldURL:      comment.URL,
+			expr.FieldLocation: offerLocations(offer),
+		},
+		Metrics: OfferMetrics(offer),
+	}
+}
+
+// offerLocations joins the locations of an offer for the location field
+func offerLocations(offer *database.Offer) string {
+	if offer == nil {
+		return ""
+	}
+	return strings.Join(offer.Locations, ", ")
 }
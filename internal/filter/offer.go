@@ -0,0 +1,551 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Offer Price And Spec Extraction"
+//   Timestamp: "2025-12-12T09:45:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Offer posts list plans as blocks, table lines or inline runs with specs before or after the price"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, KISS"
+//   Quality_Check: "Plans are split at the coarsest level holding one spec set each; missing specs stay zero"
+// }}
+
+package filter
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter/expr"
+)
+
+const (
+	// 1,000 or 1,000.50 with thousands separators, otherwise 5, 5.99 or 4,50
+	// with a decimal point or comma followed by one or two digits
+	amountNumber    = `(\d{1,3}(?:,\d{3})+(?:\.\d{1,2})?|\d+(?:[.,]\d{1,2})?)`
+	amountPattern   = amountNumber + `\b`
+	currencyPrefix  = `(US\$|C\$|A\$|\b(?:USD|EUR|GBP|CAD|AUD|CNY|RMB|JPY|INR)|[$€£¥₹])`
+	currencySuffix  = `((?:USD|EUR|GBP|CAD|AUD|CNY|RMB|JPY|INR|dollars?|euros?)\b|[€£¥])`
+	sizePattern     = `(\d+(?:\.\d+)?)\s*(MB|GB|TB|PB|MiB|GiB|TiB|M|G|T)\b`
+	sizeLabelSuffix = `\s*[:：=-]\s*(\d+(?:\.\d+)?)\s*(MB|GB|TB|PB|MiB|GiB|TiB|M|G|T)\b`
+)
+
+var (
+	// $5, US$ 5.99, €4,50, 5 USD, 10€
+	priceRe = regexp.MustCompile(`(?i)(?:` + currencyPrefix + `\s?` + amountPattern + `|` + amountNumber + `\s?` + currencySuffix + `)`)
+	// Prices that are not plan prices: "setup fee $5", "was $30", "save $10"
+	notPlanPriceRe = regexp.MustCompile(`(?i)\b(?:setup(?:\s+fee)?|fee|was|regularly|normally|instead\s+of|save|retail)\s*[:：]?\s*$`)
+	// /mo, / 2 years, per month, a year, monthly, annually
+	// A currency code after a symbol price, as in "$7 USD/yr"
+	trailingCurrencyRe = regexp.MustCompile(`(?i)^\s?` + currencySuffix)
+	periodRe           = regexp.MustCompile(`(?i)^\s*(?:(/)|(?:per|a|an|every|each|for)\s+|-\s*)?\s*(\d+)?\s*(months?|monthly|mo|mth|m|years?|yearly|annually|annual|yr|y|quarters?|quarterly|qtr|q|semi-?annually|biennially|triennially|one[- ]?time|lifetime|once)\b`)
+
+	// Each spec has a "<size> RAM" form and a "RAM: <size>" label form
+	ramRe = specPatterns{
+		regexp.MustCompile(`(?i)` + sizePattern + `\s*(?:of\s+)?(?:DDR\d\s+|ECC\s+)*(?:RAM|memory|mem)\b`),
+		regexp.MustCompile(`(?i)\b(?:RAM|memory)` + sizeLabelSuffix),
+	}
+	diskRe = specPatterns{
+		regexp.MustCompile(`(?i)` + sizePattern + `\s*(?:of\s+)?(?:RAID-?\d+\s+)?(?:NVMe|SSD|HDD|SATA|disk|storage|space|drive)`),
+		regexp.MustCompile(`(?i)\b(?:disk|storage|SSD|NVMe|HDD)(?:\s+space)?` + sizeLabelSuffix),
+	}
+	bandwidthRe = specPatterns{
+		regexp.MustCompile(`(?i)` + sizePattern + `\s*(?:/\s*mo(?:nth)?\s+)?(?:of\s+)?(?:monthly\s+)?(?:bandwidth|traffic|transfer|data|BW)\b`),
+		regexp.MustCompile(`(?i)\b(?:bandwidth|traffic|transfer|BW)` + sizeLabelSuffix),
+	}
+	unmeteredRe = regexp.MustCompile(`(?i)\b(?:unmetered|unlimited\s+(?:bandwidth|traffic))\b`)
+	cpuRe       = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(\d+)\s*(?:x\s*)?(?:dedicated\s+|shared\s+)?(?:vCPUs?|CPUs?|vCores?|cores?|CPU\s+cores?)\b`),
+		regexp.MustCompile(`(?i)\b(?:vCPUs?|CPUs?|cores?)\s*[:：=]\s*(\d+)\b`),
+	}
+
+	locationLabelRe = regexp.MustCompile(`(?im)\blocations?\s*[:：]\s*([^\n]+)`)
+	locationSplitRe = regexp.MustCompile(`\s*(?:,|/|;|\||&|\band\b)\s*`)
+
+	blankLineRe = regexp.MustCompile(`\n\s*\n`)
+)
+
+// specPatterns match one size spec
+type specPatterns struct {
+	suffix *regexp.Regexp // "<size> RAM"
+	label  *regexp.Regexp // "RAM: <size>"
+}
+
+// knownLocations are datacenter cities recognized when a post has no
+// "Location:" line
+var knownLocations = []string{
+	"Los Angeles", "San Jose", "Seattle", "Dallas", "Chicago", "New York", "Miami",
+	"Atlanta", "Phoenix", "Las Vegas", "Buffalo", "Kansas City", "Ashburn", "Toronto",
+	"Montreal", "London", "Amsterdam", "Frankfurt", "Paris", "Nuremberg", "Falkenstein",
+	"Helsinki", "Stockholm", "Warsaw", "Madrid", "Milan", "Zurich", "Singapore",
+	"Hong Kong", "Tokyo", "Osaka", "Seoul", "Taipei", "Sydney", "Mumbai", "Johannesburg",
+	"Sao Paulo",
+}
+
+var knownLocationRes = func() []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(knownLocations))
+	for i, city := range knownLocations {
+		res[i] = regexp.MustCompile(`(?i)\b` + strings.ReplaceAll(regexp.QuoteMeta(city), " ", `\s+`) + `\b`)
+	}
+	return res
+}()
+
+const (
+	maxLocations      = 10 // locations kept from one post
+	maxLocationLength = 40 // longer entries are sentences, not locations
+)
+
+// ExtractOffer parses prices, billing periods, hardware specs and locations
+// from offer text. It returns nil when the text holds no price.
+func ExtractOffer(text string) *database.Offer {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	plans := extractPlans(text)
+	if len(plans) == 0 {
+		return nil
+	}
+	return &database.Offer{Plans: plans, Locations: extractLocations(text)}
+}
+
+// OfferMetrics converts offer plans to the metrics compared by keyword rules:
+// prices in USD, sizes in GB
+func OfferMetrics(offer *database.Offer) []map[string]float64 {
+	if offer == nil {
+		return nil
+	}
+
+	metrics := make([]map[string]float64, 0, len(offer.Plans))
+	for _, plan := range offer.Plans {
+		m := map[string]float64{}
+		if rate, ok := expr.CurrencyToUSD[plan.Currency]; ok {
+			usd := plan.Price * rate
+			m[expr.MetricPrice] = usd
+			if plan.PeriodMonths > 0 {
+				m[expr.MetricPricePerMonth] = usd / float64(plan.PeriodMonths)
+				m[expr.MetricPricePerYear] = usd * 12 / float64(plan.PeriodMonths)
+			}
+		}
+		if plan.RAMGB > 0 {
+			m[expr.MetricRAM] = plan.RAMGB
+		}
+		if plan.DiskGB > 0 {
+			m[expr.MetricDisk] = plan.DiskGB
+		}
+		if plan.Unmetered {
+			m[expr.MetricBandwidth] = expr.UnmeteredBandwidth
+		} else if plan.BandwidthGB > 0 {
+			m[expr.MetricBandwidth] = plan.BandwidthGB
+		}
+		if plan.CPUCores > 0 {
+			m[expr.MetricCPU] = float64(plan.CPUCores)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// span is a match position in the text
+type span struct {
+	start, end int
+}
+
+// priceMatch is a price found in the text
+type priceMatch struct {
+	span
+	price        float64
+	currency     string
+	periodMonths int
+	oneTime      bool
+}
+
+// specMatches are the hardware specs found in a piece of text
+type specMatches struct {
+	ram, disk, bandwidth, cpu []specMatch
+	unmetered                 []span
+}
+
+// specMatch is one spec value, already converted to GB or cores
+type specMatch struct {
+	span
+	value float64
+}
+
+// extractPlans splits text into plans. Blocks separated by blank lines are
+// tried first, then lines, then the runs between prices, stopping at the
+// coarsest split where no piece holds more than one set of specs.
+func extractPlans(text string) []database.OfferPlan {
+	prices := findPrices(text)
+	if len(prices) == 0 {
+		return nil
+	}
+	if len(prices) == 1 {
+		return []database.OfferPlan{newPlan(prices[0], findSpecs(text))}
+	}
+
+	// Decide whether specs precede or follow their price from the first plan
+	specs := findSpecs(text)
+	specsFirst := specs.first() >= 0 && specs.first() < prices[0].start
+
+	for _, pieces := range [][]string{blankLineRe.Split(text, -1), strings.Split(text, "\n")} {
+		if plans, ok := plansFromPieces(pieces, specsFirst, false); ok {
+			return plans
+		}
+	}
+	plans, _ := plansFromPieces(splitAtPrices(text, prices, specsFirst), specsFirst, true)
+	return plans
+}
+
+// plansFromPieces assigns spec-only pieces to the price piece they belong to.
+// It fails when a piece holds several spec sets, unless force is set.
+func plansFromPieces(pieces []string, specsFirst, force bool) ([]database.OfferPlan, bool) {
+	var plans []database.OfferPlan
+	var pending specMatches // specs-first layout: specs seen since the last price
+	current := 0            // price-first layout: plans started by the last price piece
+
+	for _, piece := range pieces {
+		prices := findPrices(piece)
+		specs := findSpecs(piece)
+		if !force && specs.sets() > 1 && len(prices) > 0 {
+			return nil, false
+		}
+
+		if len(prices) == 0 {
+			if specsFirst {
+				pending = pending.merge(specs)
+			} else {
+				for i := len(plans) - current; i < len(plans); i++ {
+					fillPlan(&plans[i], specs)
+				}
+			}
+			continue
+		}
+
+		// Several prices with one spec set are billing options of the same plan
+		if specsFirst {
+			specs = specs.merge(pending)
+			pending = specMatches{}
+		}
+		for _, price := range prices {
+			plans = append(plans, newPlan(price, specs))
+		}
+		current = len(prices)
+	}
+
+	// Specs after the last price in a specs-first post belong to the last plan
+	if specsFirst && len(plans) > 0 {
+		fillPlan(&plans[len(plans)-1], pending)
+	}
+	return plans, true
+}
+
+// splitAtPrices cuts text into one piece per price. Text between two prices
+// goes with the following price when specs come first, else with the preceding one.
+func splitAtPrices(text string, prices []priceMatch, specsFirst bool) []string {
+	pieces := make([]string, 0, len(prices))
+	start := 0
+	for i := range prices {
+		var end int
+		switch {
+		case i == len(prices)-1:
+			end = len(text)
+		case specsFirst:
+			end = prices[i].end
+		default:
+			end = prices[i+1].start
+		}
+		pieces = append(pieces, text[start:end])
+		start = end
+	}
+	return pieces
+}
+
+// findPrices returns the prices in text with their billing periods
+func findPrices(text string) []priceMatch {
+	var prices []priceMatch
+	for _, m := range priceRe.FindAllStringSubmatchIndex(text, -1) {
+		var symbol, amount string
+		if m[2] >= 0 {
+			symbol, amount = text[m[2]:m[3]], text[m[4]:m[5]]
+		} else {
+			amount, symbol = text[m[6]:m[7]], text[m[8]:m[9]]
+		}
+
+		price, err := parseAmount(amount)
+		if err != nil || price <= 0 {
+			continue
+		}
+
+		pm := priceMatch{span: span{m[0], m[1]}, price: price, currency: currencyCode(symbol)}
+		if pm.currency == "" || notPlanPriceRe.MatchString(text[max(0, m[0]-24):m[0]]) {
+			continue
+		}
+		pm.periodMonths, pm.oneTime, pm.end = parsePeriod(text, m[1])
+		prices = append(prices, pm)
+	}
+	return prices
+}
+
+// parseAmount parses an amount matched by amountNumber: a comma followed by
+// three digits separates thousands, any other comma is a decimal comma
+func parseAmount(amount string) (float64, error) {
+	if i := strings.LastIndexByte(amount, ','); i >= 0 {
+		if strings.Contains(amount, ".") || len(amount)-i == 4 {
+			amount = strings.ReplaceAll(amount, ",", "")
+		} else {
+			amount = amount[:i] + "." + amount[i+1:]
+		}
+	}
+	return strconv.ParseFloat(amount, 64)
+}
+
+// currencyCode normalizes the currency spellings matched by priceRe
+func currencyCode(symbol string) string {
+	switch strings.ToLower(symbol) {
+	case "dollar", "dollars":
+		return "USD"
+	case "euro", "euros":
+		return "EUR"
+	}
+	return expr.CurrencyCode(symbol)
+}
+
+// parsePeriod parses the billing period following a price at offset end,
+// skipping a currency code repeated after the price. It returns the period
+// in months and the offset past the period.
+func parsePeriod(text string, end int) (months int, oneTime bool, newEnd int) {
+	start := end
+	if m := trailingCurrencyRe.FindStringIndex(text[end:]); m != nil {
+		end += m[1]
+	}
+
+	m := periodRe.FindStringSubmatchIndex(text[end:])
+	if m == nil {
+		return 0, false, start
+	}
+
+	slash := m[2] >= 0
+	count := 1
+	if m[4] >= 0 {
+		count, _ = strconv.Atoi(text[end+m[4] : end+m[5]])
+	}
+	unit := strings.ToLower(text[end+m[6] : end+m[7]])
+
+	// Single letters are only units right after a slash, as in $5/m
+	if len(unit) == 1 && !slash {
+		return 0, false, start
+	}
+
+	switch {
+	case strings.HasPrefix(unit, "mo"), unit == "mth", unit == "m":
+		months = 1
+	case strings.HasPrefix(unit, "q"):
+		months = 3
+	case strings.HasPrefix(unit, "semi"):
+		months = 6
+	case strings.HasPrefix(unit, "bi"):
+		months = 24
+	case strings.HasPrefix(unit, "tri"):
+		months = 36
+	case strings.HasPrefix(unit, "one"), unit == "lifetime", unit == "once":
+		return 0, true, end + m[1]
+	default:
+		months = 12
+	}
+	if count > 0 {
+		months *= count
+	}
+	return months, false, end + m[1]
+}
+
+// findSpecs returns the hardware specs in text
+func findSpecs(text string) specMatches {
+	var specs specMatches
+
+	// Label forms go first: in "RAM: 1GB Disk: 15GB" the suffix form would
+	// read "1GB Disk" as the disk size
+	specs.ram = findSizes(text, ramRe.label, nil)
+	specs.disk = findSizes(text, diskRe.label, nil)
+	specs.bandwidth = findSizes(text, bandwidthRe.label, nil)
+	var claimed []specMatch
+	for _, list := range [][]specMatch{specs.ram, specs.disk, specs.bandwidth} {
+		claimed = append(claimed, list...)
+	}
+	specs.ram = append(specs.ram, findSizes(text, ramRe.suffix, claimed)...)
+	specs.disk = append(specs.disk, findSizes(text, diskRe.suffix, claimed)...)
+	specs.bandwidth = append(specs.bandwidth, findSizes(text, bandwidthRe.suffix, claimed)...)
+	sortSpecs(specs.ram)
+	sortSpecs(specs.disk)
+	sortSpecs(specs.bandwidth)
+
+	for _, m := range unmeteredRe.FindAllStringIndex(text, -1) {
+		specs.unmetered = append(specs.unmetered, span{m[0], m[1]})
+	}
+	for _, re := range cpuRe {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			cores, err := strconv.Atoi(text[m[2]:m[3]])
+			if err != nil || cores == 0 {
+				continue
+			}
+			specs.cpu = addSpec(specs.cpu, specMatch{span{m[0], m[1]}, float64(cores)})
+		}
+	}
+	return specs
+}
+
+// findSizes returns the sizes matched by re in GB, skipping matches that
+// overlap a claimed span
+func findSizes(text string, re *regexp.Regexp, claimed []specMatch) []specMatch {
+	var sizes []specMatch
+	for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+		value, err := strconv.ParseFloat(text[m[2]:m[3]], 64)
+		if err != nil {
+			continue
+		}
+		gb, ok := expr.SizeToGB(value, text[m[4]:m[5]])
+		if !ok || gb <= 0 || overlaps(claimed, span{m[0], m[1]}) {
+			continue
+		}
+		sizes = append(sizes, specMatch{span{m[0], m[1]}, gb})
+	}
+	return sizes
+}
+
+// addSpec appends a match unless it overlaps one already found by another pattern
+func addSpec(specs []specMatch, m specMatch) []specMatch {
+	if overlaps(specs, m.span) {
+		return specs
+	}
+	return append(specs, m)
+}
+
+// overlaps reports whether s overlaps any of the matches
+func overlaps(matches []specMatch, s span) bool {
+	for _, m := range matches {
+		if s.start < m.end && m.start < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSpecs orders matches by position so that the first one is the first in the text
+func sortSpecs(specs []specMatch) {
+	sort.Slice(specs, func(i, j int) bool { return specs[i].start < specs[j].start })
+}
+
+// sets returns how many plans' worth of specs were found
+func (s specMatches) sets() int {
+	n := 0
+	for _, count := range []int{len(s.ram), len(s.disk), max(len(s.bandwidth), len(s.unmetered)), len(s.cpu)} {
+		if count > n {
+			n = count
+		}
+	}
+	return n
+}
+
+// first returns the offset of the earliest spec, or -1 when there is none
+func (s specMatches) first() int {
+	first := -1
+	consider := func(start int) {
+		if first < 0 || start < first {
+			first = start
+		}
+	}
+	for _, list := range [][]specMatch{s.ram, s.disk, s.bandwidth, s.cpu} {
+		for _, m := range list {
+			consider(m.start)
+		}
+	}
+	for _, m := range s.unmetered {
+		consider(m.start)
+	}
+	return first
+}
+
+// merge keeps s and adds the specs of other that s lacks
+func (s specMatches) merge(other specMatches) specMatches {
+	if len(s.ram) == 0 {
+		s.ram = other.ram
+	}
+	if len(s.disk) == 0 {
+		s.disk = other.disk
+	}
+	if len(s.bandwidth) == 0 && len(s.unmetered) == 0 {
+		s.bandwidth, s.unmetered = other.bandwidth, other.unmetered
+	}
+	if len(s.cpu) == 0 {
+		s.cpu = other.cpu
+	}
+	return s
+}
+
+// newPlan builds a plan from a price and the first spec of each kind
+func newPlan(price priceMatch, specs specMatches) database.OfferPlan {
+	plan := database.OfferPlan{
+		Price:        price.price,
+		Currency:     price.currency,
+		PeriodMonths: price.periodMonths,
+		OneTime:      price.oneTime,
+	}
+	fillPlan(&plan, specs)
+	return plan
+}
+
+// fillPlan sets the specs a plan does not have yet
+func fillPlan(plan *database.OfferPlan, specs specMatches) {
+	if plan.RAMGB == 0 && len(specs.ram) > 0 {
+		plan.RAMGB = round(specs.ram[0].value)
+	}
+	if plan.DiskGB == 0 && len(specs.disk) > 0 {
+		plan.DiskGB = round(specs.disk[0].value)
+	}
+	if plan.BandwidthGB == 0 && !plan.Unmetered {
+		if len(specs.unmetered) > 0 {
+			plan.Unmetered = true
+		} else if len(specs.bandwidth) > 0 {
+			plan.BandwidthGB = round(specs.bandwidth[0].value)
+		}
+	}
+	if plan.CPUCores == 0 && len(specs.cpu) > 0 {
+		plan.CPUCores = int(specs.cpu[0].value)
+	}
+}
+
+// round keeps three decimals so that MB sizes stay readable in GB
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// extractLocations returns the locations named on "Location:" lines or,
+// failing that, the known datacenter cities mentioned in text
+func extractLocations(text string) []string {
+	var locations []string
+	seen := map[string]bool{}
+	add := func(location string) {
+		location = strings.Trim(location, " \t.*-")
+		key := strings.ToLower(location)
+		if location == "" || len(location) > maxLocationLength || seen[key] || len(locations) >= maxLocations {
+			return
+		}
+		seen[key] = true
+		locations = append(locations, location)
+	}
+
+	for _, m := range locationLabelRe.FindAllStringSubmatch(text, -1) {
+		for _, location := range locationSplitRe.Split(m[1], -1) {
+			add(location)
+		}
+	}
+	if len(locations) > 0 {
+		return locations
+	}
+
+	for i, re := range knownLocationRes {
+		if re.MatchString(text) {
+			add(knownLocations[i])
+		}
+	}
+	return locations
+}
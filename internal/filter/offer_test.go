@@ -0,0 +1,263 @@
+package filter
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter/expr"
+)
+
+func TestExtractOfferPrices(t *testing.T) {
+	tests := []struct {
+		text     string
+		price    float64
+		currency string
+		months   int
+	}{
+		{"KVM 1GB RAM $5/mo", 5, "USD", 1},
+		{"US$ 5.99 per month", 5.99, "USD", 1},
+		{"€4,50/mo", 4.5, "EUR", 1},
+		{"10,5€ monthly", 10.5, "EUR", 1},
+		{"Dedicated server $1,000/yr", 1000, "USD", 12},
+		{"$1,299.99 per year", 1299.99, "USD", 12},
+		{"$12,345 / 2 years", 12345, "USD", 24},
+		{"1,500 USD annually", 1500, "USD", 12},
+		{"$7 USD/yr", 7, "USD", 12},
+		{"€30 EUR per year", 30, "EUR", 12},
+		{"$7 USD", 7, "USD", 0},
+	}
+
+	for _, tt := range tests {
+		offer := ExtractOffer(tt.text)
+		if offer == nil || len(offer.Plans) != 1 {
+			t.Errorf("ExtractOffer(%q) = %+v, want one plan", tt.text, offer)
+			continue
+		}
+		plan := offer.Plans[0]
+		if plan.Price != tt.price || plan.Currency != tt.currency || plan.PeriodMonths != tt.months {
+			t.Errorf("ExtractOffer(%q) = %v %s every %d months, want %v %s every %d months",
+				tt.text, plan.Price, plan.Currency, plan.PeriodMonths, tt.price, tt.currency, tt.months)
+		}
+	}
+}
+
+func TestExtractOfferPeriods(t *testing.T) {
+	tests := []struct {
+		text    string
+		months  int
+		oneTime bool
+	}{
+		{"$3/m", 1, false},
+		{"$3 m", 0, false}, // a single letter is only a unit after a slash
+		{"$10 quarterly", 3, false},
+		{"$20 semi-annually", 6, false},
+		{"$4 every 3 months", 3, false},
+		{"$50 biennially", 24, false},
+		{"$90 triennially", 36, false},
+		{"$15 one-time", 0, true},
+		{"$99 lifetime", 0, true},
+		{"$5", 0, false},
+	}
+
+	for _, tt := range tests {
+		offer := ExtractOffer(tt.text)
+		if offer == nil || len(offer.Plans) != 1 {
+			t.Errorf("ExtractOffer(%q) = %+v, want one plan", tt.text, offer)
+			continue
+		}
+		plan := offer.Plans[0]
+		if plan.PeriodMonths != tt.months || plan.OneTime != tt.oneTime {
+			t.Errorf("ExtractOffer(%q) period = %d months, one-time %v, want %d months, one-time %v",
+				tt.text, plan.PeriodMonths, plan.OneTime, tt.months, tt.oneTime)
+		}
+	}
+}
+
+func TestExtractOfferSpecs(t *testing.T) {
+	tests := []struct {
+		text string
+		plan database.OfferPlan
+	}{
+		{
+			"KVM VPS\n2 vCPU, 4GB RAM, 50GB NVMe, 2TB bandwidth\n$6/mo",
+			database.OfferPlan{Price: 6, Currency: "USD", PeriodMonths: 1, RAMGB: 4, DiskGB: 50, BandwidthGB: 2048, CPUCores: 2},
+		},
+		{
+			"RAM: 512MB\nDisk: 10GB SSD\nTraffic: 1TB\nCPU: 1\nPrice: $12/yr",
+			database.OfferPlan{Price: 12, Currency: "USD", PeriodMonths: 12, RAMGB: 0.5, DiskGB: 10, BandwidthGB: 1024, CPUCores: 1},
+		},
+		{
+			"1 core 1GB RAM 20GB SSD unmetered $3/mo",
+			database.OfferPlan{Price: 3, Currency: "USD", PeriodMonths: 1, RAMGB: 1, DiskGB: 20, Unmetered: true, CPUCores: 1},
+		},
+		{
+			"setup fee $5, 1GB RAM $3/mo",
+			database.OfferPlan{Price: 3, Currency: "USD", PeriodMonths: 1, RAMGB: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		offer := ExtractOffer(tt.text)
+		if offer == nil || len(offer.Plans) != 1 {
+			t.Errorf("ExtractOffer(%q) = %+v, want one plan", tt.text, offer)
+			continue
+		}
+		if offer.Plans[0] != tt.plan {
+			t.Errorf("ExtractOffer(%q) = %+v, want %+v", tt.text, offer.Plans[0], tt.plan)
+		}
+	}
+
+	if offer := ExtractOffer("no price here 1GB RAM"); offer != nil {
+		t.Errorf("ExtractOffer without a price = %+v, want nil", offer)
+	}
+}
+
+func TestExtractOfferLocations(t *testing.T) {
+	tests := []struct {
+		text      string
+		locations []string
+	}{
+		{"Location: Los Angeles, New York / Amsterdam\n$5/mo", []string{"Los Angeles", "New York", "Amsterdam"}},
+		{"Deals in Frankfurt and Singapore. 1GB RAM $20/yr", []string{"Frankfurt", "Singapore"}},
+		{"Location: Dallas\nAlso available in Tokyo. $5/mo", []string{"Dallas"}}, // a label wins over known cities
+		{"1GB RAM $5/mo", nil},
+	}
+
+	for _, tt := range tests {
+		offer := ExtractOffer(tt.text)
+		if offer == nil {
+			t.Errorf("ExtractOffer(%q) = nil", tt.text)
+			continue
+		}
+		if !reflect.DeepEqual(offer.Locations, tt.locations) {
+			t.Errorf("ExtractOffer(%q) locations = %q, want %q", tt.text, offer.Locations, tt.locations)
+		}
+	}
+}
+
+func TestExtractOfferPlans(t *testing.T) {
+	small := database.OfferPlan{Price: 10, Currency: "USD", PeriodMonths: 12, RAMGB: 1, DiskGB: 20}
+	large := database.OfferPlan{Price: 18, Currency: "USD", PeriodMonths: 12, RAMGB: 2, DiskGB: 40}
+
+	tests := []struct {
+		name  string
+		text  string
+		plans []database.OfferPlan
+	}{
+		{
+			"blocks",
+			"Plan A\n1GB RAM\n20GB SSD\n$10/yr\n\nPlan B\n2GB RAM\n40GB SSD\n$18/yr",
+			[]database.OfferPlan{small, large},
+		},
+		{
+			"lines with specs first",
+			"1GB RAM 20GB SSD - $10/yr\n2GB RAM 40GB SSD - $18/yr",
+			[]database.OfferPlan{small, large},
+		},
+		{
+			"lines with price first",
+			"$10/yr: 1GB RAM, 20GB SSD\n$18/yr: 2GB RAM, 40GB SSD",
+			[]database.OfferPlan{small, large},
+		},
+		{
+			"inline",
+			"1GB RAM $10/yr 2GB RAM $18/yr",
+			[]database.OfferPlan{
+				{Price: 10, Currency: "USD", PeriodMonths: 12, RAMGB: 1},
+				{Price: 18, Currency: "USD", PeriodMonths: 12, RAMGB: 2},
+			},
+		},
+		{
+			"billing options of one plan",
+			"2GB RAM: $5/mo or $50/yr",
+			[]database.OfferPlan{
+				{Price: 5, Currency: "USD", PeriodMonths: 1, RAMGB: 2},
+				{Price: 50, Currency: "USD", PeriodMonths: 12, RAMGB: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		offer := ExtractOffer(tt.text)
+		if offer == nil {
+			t.Errorf("%s: ExtractOffer = nil", tt.name)
+			continue
+		}
+		if !reflect.DeepEqual(offer.Plans, tt.plans) {
+			t.Errorf("%s: ExtractOffer plans = %+v, want %+v", tt.name, offer.Plans, tt.plans)
+		}
+	}
+}
+
+func TestOfferMetrics(t *testing.T) {
+	offer := &database.Offer{Plans: []database.OfferPlan{
+		{Price: 30, Currency: "EUR", PeriodMonths: 12, RAMGB: 1, DiskGB: 20, Unmetered: true, CPUCores: 2},
+		{Price: 5, Currency: "USD", OneTime: true, BandwidthGB: 500},
+		{Price: 100, Currency: "XYZ", RAMGB: 4},
+	}}
+	want := []map[string]float64{
+		{
+			expr.MetricPrice:         32.4,
+			expr.MetricPricePerMonth: 2.7,
+			expr.MetricPricePerYear:  32.4,
+			expr.MetricRAM:           1,
+			expr.MetricDisk:          20,
+			expr.MetricBandwidth:     expr.UnmeteredBandwidth,
+			expr.MetricCPU:           2,
+		},
+		{expr.MetricPrice: 5, expr.MetricBandwidth: 500},
+		{expr.MetricRAM: 4}, // unknown currency: no price metrics
+	}
+
+	got := OfferMetrics(offer)
+	if len(got) != len(want) {
+		t.Fatalf("OfferMetrics returned %d plans, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Errorf("plan %d metrics = %v, want %v", i, got[i], want[i])
+			continue
+		}
+		for metric, value := range want[i] {
+			if math.Abs(got[i][metric]-value) > 1e-9 {
+				t.Errorf("plan %d %s = %v, want %v", i, metric, got[i][metric], value)
+			}
+		}
+	}
+
+	if OfferMetrics(nil) != nil {
+		t.Error("OfferMetrics(nil) should be nil")
+	}
+}
+
+func TestOfferThresholdRules(t *testing.T) {
+	text := "Plan A\n1GB RAM\n20GB SSD\n$10/yr\n\nPlan B\n2GB RAM\n40GB SSD\n$18/yr"
+	tests := []struct {
+		rule  string
+		match bool
+	}{
+		{"price_per_year <= 10", true},
+		{"price_per_year < 10", false},
+		{"price_per_month < 1 USD", true},
+		{"ram >= 2GB AND price_per_year <= 15", false}, // no single plan satisfies both
+		{"ram >= 2GB AND price_per_year <= 20", true},
+		{"ram >= 2048MB", true},
+		{"disk > 40GB", false},
+		{"price_per_year < 10 EUR", true}, // 10 EUR is 10.8 USD
+		{"cpu >= 1", false},               // no plan lists cores
+	}
+
+	doc := expr.Document{Text: text, Metrics: OfferMetrics(ExtractOffer(text))}
+	for _, tt := range tests {
+		rule, err := expr.Compile(tt.rule)
+		if err != nil {
+			t.Errorf("Compile(%q) error: %v", tt.rule, err)
+			continue
+		}
+		if _, got := rule.Match(doc); got != tt.match {
+			t.Errorf("%q on two plans = %v, want %v", tt.rule, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Ollama Local Model Filter"
+//   Timestamp: "2025-12-15T14:40:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Running a local model required an OpenAI-compatible shim and a dummy API key"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Interface Segregation"
+//   Quality_Check: "Native /api/chat without streaming; JSON mode via the format schema"
+// }}
+
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// OllamaFilter filters content using a local Ollama server
+type OllamaFilter struct {
+	chatURL    string
+	model      string
+	outputMode string
+	client     *http.Client
+}
+
+// NewOllamaFilter creates a new Ollama filter. baseURL is the server address,
+// e.g. http://localhost:11434; an empty baseURL uses that default.
+// outputMode is config.AIOutputText or config.AIOutputJSON.
+func NewOllamaFilter(baseURL, model, outputMode string) *OllamaFilter {
+	if baseURL == "" {
+		baseURL = config.DefaultOllamaURL
+	}
+	return &OllamaFilter{
+		chatURL:    strings.TrimRight(baseURL, "/") + "/api/chat",
+		model:      model,
+		outputMode: outputMode,
+		client: &http.Client{
+			// Local models may need to be loaded into memory first
+			Timeout: 180 * time.Second,
+		},
+	}
+}
+
+// OllamaRequest represents the request to the Ollama chat API
+type OllamaRequest struct {
+	Model    string      `json:"model"`
+	Messages []Message   `json:"messages"`
+	Stream   bool        `json:"stream"`
+	Format   interface{} `json:"format,omitempty"` // JSON schema of the reply
+}
+
+// OllamaResponse represents the non-streaming response of the Ollama chat API
+type OllamaResponse struct {
+	Model     string  `json:"model"`
+	CreatedAt string  `json:"created_at"`
+	Message   Message `json:"message"`
+	Done      bool    `json:"done"`
+	// Token counts of the prompt and the reply
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// OllamaError represents an error response of the Ollama API
+type OllamaError struct {
+	Error string `json:"error"`
+}
+
+// Filter sends content to Ollama and returns the filtered result
+func (f *OllamaFilter) Filter(content string, prompt string) (string, error) {
+	result, _, err := f.filterWithUsage(content, prompt)
+	return result, err
+}
+
+// filterWithUsage is Filter, also returning the token usage of the request
+func (f *OllamaFilter) filterWithUsage(content string, prompt string) (string, Usage, error) {
+	start := time.Now()
+	result, usage, err := f.send(content, prompt)
+	metrics.ObserveAIRequest("ollama", f.model, err, start)
+	if err != nil {
+		return "", usage, err
+	}
+
+	result = normalizeResult(result)
+
+	log.Debugf("Ollama AI 过滤结果: %s", result)
+	return result, usage, nil
+}
+
+// send posts the prompt and content to Ollama and returns the raw reply
+func (f *OllamaFilter) send(content string, prompt string) (string, Usage, error) {
+	req := OllamaRequest{
+		Model: f.model,
+		Messages: []Message{
+			{Role: "system", Content: verdictPrompt(prompt, f.outputMode)},
+			{Role: "user", Content: content},
+		},
+	}
+	if f.outputMode == config.AIOutputJSON {
+		req.Format = verdictSchema
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("无法序列化请求: %w", err)
+	}
+
+	resp, err := f.client.Post(f.chatURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("Ollama 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("无法读取响应: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr OllamaError
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return "", Usage{}, fmt.Errorf("Ollama 返回错误状态码 %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return "", Usage{}, fmt.Errorf("Ollama 返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var ollamaResp OllamaResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return "", Usage{}, fmt.Errorf("无法解析响应: %w", err)
+	}
+
+	log.Debugf("Token 使用量 - Prompt: %d, Completion: %d", ollamaResp.PromptEvalCount, ollamaResp.EvalCount)
+	usage := Usage{PromptTokens: ollamaResp.PromptEvalCount, CompletionTokens: ollamaResp.EvalCount}
+	metrics.AddAITokens("ollama", f.model, usage.PromptTokens, usage.CompletionTokens)
+
+	if ollamaResp.Message.Content == "" {
+		return "", usage, fmt.Errorf("Ollama 返回空结果")
+	}
+
+	return ollamaResp.Message.Content, usage, nil
+}
+
+// IsValidResult checks if the AI result accepts the content: a JSON verdict
+// marked relevant, or a text reply that does not start with "FALSE"
+func (f *OllamaFilter) IsValidResult(result string) bool {
+	return isRelevant(result)
+}
@@ -17,25 +17,34 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
 // OpenAIFilter filters content using OpenAI-compatible API
 type OpenAIFilter struct {
-	apiURL string
-	apiKey string
-	model  string
-	client *http.Client
+	apiURL     string
+	apiKey     string
+	model      string
+	outputMode string
+	client     *http.Client
+
+	// set once the API rejected response_format; the JSON instructions in
+	// the prompt are then the only way to get a verdict
+	schemaUnsupported atomic.Bool
 }
 
-// NewOpenAIFilter creates a new OpenAI-compatible filter
-func NewOpenAIFilter(apiURL, apiKey, model string) *OpenAIFilter {
+// NewOpenAIFilter creates a new OpenAI-compatible filter. outputMode is config.AIOutputText or config.AIOutputJSON.
+func NewOpenAIFilter(apiURL, apiKey, model, outputMode string) *OpenAIFilter {
 	return &OpenAIFilter{
-		apiURL: apiURL,
-		apiKey: apiKey,
-		model:  model,
+		apiURL:     apiURL,
+		apiKey:     apiKey,
+		model:      model,
+		outputMode: outputMode,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -50,8 +59,22 @@ type OpenAIMessage struct {
 
 // OpenAIRequest represents the request to OpenAI-compatible API
 type OpenAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []OpenAIMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []OpenAIMessage       `json:"messages"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenAIResponseFormat requests structured output matching a JSON schema
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema"`
+}
+
+// OpenAIJSONSchema is the named schema of a structured output request
+type OpenAIJSONSchema struct {
+	Name   string      `json:"name"`
+	Strict bool        `json:"strict"`
+	Schema interface{} `json:"schema"`
 }
 
 // OpenAIResponse represents the response from OpenAI-compatible API
@@ -77,22 +100,69 @@ type OpenAIResponse struct {
 
 // Filter sends content to OpenAI-compatible API and returns the filtered result
 func (f *OpenAIFilter) Filter(content string, prompt string) (string, error) {
+	result, _, err := f.filterWithUsage(content, prompt)
+	return result, err
+}
+
+// filterWithUsage is Filter, also returning the token usage of the request
+func (f *OpenAIFilter) filterWithUsage(content string, prompt string) (string, Usage, error) {
+	start := time.Now()
+	result, usage, err := f.chat(content, prompt)
+	metrics.ObserveAIRequest("openai", f.model, err, start)
+	if err != nil {
+		return "", usage, err
+	}
+
+	result = normalizeResult(result)
+
+	log.Debugf("OpenAI AI 过滤结果: %s", result)
+	return result, usage, nil
+}
+
+// chat sends the prompt and content to the OpenAI-compatible API and returns the raw reply
+func (f *OpenAIFilter) chat(content string, prompt string) (string, Usage, error) {
 	req := OpenAIRequest{
 		Model: f.model,
 		Messages: []OpenAIMessage{
-			{Role: "system", Content: prompt},
+			{Role: "system", Content: verdictPrompt(prompt, f.outputMode)},
 			{Role: "user", Content: content},
 		},
 	}
+	if f.outputMode == config.AIOutputJSON && !f.schemaUnsupported.Load() {
+		req.ResponseFormat = &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: "verdict", Strict: true, Schema: verdictSchema},
+		}
+	}
 
+	reply, usage, status, err := f.send(req)
+	if err != nil && status == http.StatusBadRequest && req.ResponseFormat != nil && schemaRejected(err) {
+		// Many OpenAI-compatible servers do not implement structured output
+		log.Warnf("OpenAI API 不支持 response_format，改为仅通过提示词要求 JSON: %v", err)
+		f.schemaUnsupported.Store(true)
+		req.ResponseFormat = nil
+		reply, usage, _, err = f.send(req)
+	}
+	return reply, usage, err
+}
+
+// schemaRejected reports whether a 400 error names the structured output
+// parameters, as opposed to e.g. an over-long prompt or an unknown model
+func schemaRejected(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "response_format") || strings.Contains(msg, "json_schema")
+}
+
+// send posts a chat request and returns the reply, its token usage and the HTTP status code
+func (f *OpenAIFilter) send(req OpenAIRequest) (string, Usage, int, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("无法序列化请求: %w", err)
+		return "", Usage{}, 0, fmt.Errorf("无法序列化请求: %w", err)
 	}
 
 	httpReq, err := http.NewRequest("POST", f.apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("无法创建请求: %w", err)
+		return "", Usage{}, 0, fmt.Errorf("无法创建请求: %w", err)
 	}
 
 	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", f.apiKey))
@@ -100,48 +170,44 @@ func (f *OpenAIFilter) Filter(content string, prompt string) (string, error) {
 
 	resp, err := f.client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API 请求失败: %w", err)
+		return "", Usage{}, 0, fmt.Errorf("OpenAI API 请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("无法读取响应: %w", err)
+		return "", Usage{}, 0, fmt.Errorf("无法读取响应: %w", err)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OpenAI API 返回错误状态码 %d: %s", resp.StatusCode, string(body))
+		return "", Usage{}, resp.StatusCode, fmt.Errorf("OpenAI API 返回错误状态码 %d: %s", resp.StatusCode, string(body))
 	}
 
 	var openAIResp OpenAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", fmt.Errorf("无法解析响应: %w", err)
-	}
-
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("OpenAI API 返回空结果")
+		return "", Usage{}, resp.StatusCode, fmt.Errorf("无法解析响应: %w", err)
 	}
 
-	result := openAIResp.Choices[0].Message.Content
-
-	// Extract content before "END" marker (consistent with Cloudflare implementation)
-	if idx := strings.Index(result, "END"); idx >= 0 {
-		result = result[:idx]
-	}
-
-	result = strings.TrimSpace(result)
-
-	log.Debugf("OpenAI AI 过滤结果: %s", result)
 	log.Debugf("Token 使用量 - Prompt: %d, Completion: %d, Total: %d",
 		openAIResp.Usage.PromptTokens,
 		openAIResp.Usage.CompletionTokens,
 		openAIResp.Usage.TotalTokens)
+	usage := Usage{
+		PromptTokens:     openAIResp.Usage.PromptTokens,
+		CompletionTokens: openAIResp.Usage.CompletionTokens,
+	}
+	metrics.AddAITokens("openai", f.model, usage.PromptTokens, usage.CompletionTokens)
+
+	if len(openAIResp.Choices) == 0 {
+		return "", usage, resp.StatusCode, fmt.Errorf("OpenAI API 返回空结果")
+	}
 
-	return result, nil
+	return openAIResp.Choices[0].Message.Content, usage, resp.StatusCode, nil
 }
 
-// IsValidResult checks if the AI result is valid (not "FALSE")
+// IsValidResult checks if the AI result accepts the content: a JSON verdict
+// marked relevant, or a text reply that does not start with "FALSE"
 func (f *OpenAIFilter) IsValidResult(result string) bool {
-	return strings.ToUpper(strings.TrimSpace(result)) != "FALSE"
+	return isRelevant(result)
 }
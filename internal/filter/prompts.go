@@ -0,0 +1,49 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Prompt Templates"
+//   Timestamp: "2025-12-17T09:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Prompt templates need the same thread and comment view in the monitor and the preview endpoint"
+//   Principle_Applied: "Aether-Engineering-DRY"
+//   Quality_Check: "One mapping from stored records to prompt data; system prompt built like the providers do"
+// }}
+
+package filter
+
+import (
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter/prompt"
+)
+
+// ThreadPromptData exposes a thread to the thread prompt template
+func ThreadPromptData(thread *database.Thread) prompt.Data {
+	return prompt.Data{
+		Kind:        "thread",
+		Title:       thread.Title,
+		Link:        thread.Link,
+		Domain:      thread.Domain,
+		Category:    thread.Category,
+		Creator:     thread.Creator,
+		PubDate:     thread.PubDate,
+		OpeningPost: thread.Description,
+	}
+}
+
+// CommentPromptData exposes a comment to the comment prompt template, together
+// with its thread so the model sees what the comment replies to
+func CommentPromptData(thread *database.Thread, comment *database.Comment) prompt.Data {
+	data := ThreadPromptData(thread)
+	data.Kind = "comment"
+	data.Author = comment.Author
+	data.Role = comment.Role
+	data.Message = comment.Message
+	data.URL = comment.URL
+	data.CreatedAt = comment.CreatedAt
+	return data
+}
+
+// SystemPrompt returns the system message sent to the provider for a rendered
+// prompt, including the verdict instructions in JSON output mode
+func SystemPrompt(rendered, outputMode string) string {
+	return verdictPrompt(rendered, outputMode)
+}
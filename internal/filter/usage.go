@@ -0,0 +1,163 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Usage Accounting And Budgets"
+//   Timestamp: "2025-12-16T09:20:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Token usage was only logged at debug level, Cloudflare usage not at all, and spend had no limit"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Decorator Pattern"
+//   Quality_Check: "Every provider reports usage; totals survive restarts; budgets reset at midnight UTC"
+// }}
+
+package filter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Usage is the token usage of one AI request
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// usageReporter is implemented by provider filters that report token usage
+type usageReporter interface {
+	filterWithUsage(content string, prompt string) (string, Usage, error)
+}
+
+// UsageStore persists AI usage; database.Database implements it
+type UsageStore interface {
+	InsertAIUsage(usage *database.AIUsage) error
+	SummarizeAIUsage(since, until time.Time) ([]*database.AIUsageSummary, error)
+}
+
+// UsageTracker records AI usage and keeps the totals of the current UTC day
+// for budget checks. It outlives configuration reloads.
+type UsageTracker struct {
+	store UsageStore
+
+	mu     sync.Mutex
+	day    string // UTC date the totals belong to
+	tokens int64
+	cost   float64
+	warned bool // budget exhaustion logged for day
+}
+
+// NewUsageTracker creates a tracker persisting usage in store
+func NewUsageTracker(store UsageStore) *UsageTracker {
+	return &UsageTracker{store: store}
+}
+
+// Record persists the usage of one request and adds it to today's totals
+func (t *UsageTracker) Record(usage *database.AIUsage) {
+	t.mu.Lock()
+	t.rollover(usage.CreatedAt)
+	t.tokens += int64(usage.PromptTokens + usage.CompletionTokens)
+	t.cost += usage.Cost
+	t.mu.Unlock()
+
+	if err := t.store.InsertAIUsage(usage); err != nil {
+		log.Warnf("记录 AI 用量失败: %v", err)
+	}
+}
+
+// Today returns the tokens and cost used since midnight UTC
+func (t *UsageTracker) Today() (int64, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover(time.Now())
+	return t.tokens, t.cost
+}
+
+// OverBudget reports whether today's usage reached the token or cost budget.
+// A zero budget is unlimited.
+func (t *UsageTracker) OverBudget(tokenBudget int64, costBudget float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover(time.Now())
+	over := (tokenBudget > 0 && t.tokens >= tokenBudget) || (costBudget > 0 && t.cost >= costBudget)
+	if over && !t.warned {
+		t.warned = true
+		log.Warnf("今日 AI 用量已达预算 (Token %d，费用 $%.4f)，今天剩余时间只使用关键词过滤", t.tokens, t.cost)
+	}
+	return over
+}
+
+// rollover starts a new day's totals, loading usage already stored for that
+// day so that restarts do not reset the budget. The caller holds t.mu.
+func (t *UsageTracker) rollover(now time.Time) {
+	day := now.UTC().Format("2006-01-02")
+	if day == t.day {
+		return
+	}
+
+	t.day = day
+	t.tokens, t.cost, t.warned = 0, 0, false
+
+	since, _ := time.Parse("2006-01-02", day)
+	summaries, err := t.store.SummarizeAIUsage(since, since.Add(24*time.Hour))
+	if err != nil {
+		log.Warnf("读取今日 AI 用量失败: %v", err)
+		return
+	}
+	for _, s := range summaries {
+		t.tokens += s.PromptTokens + s.CompletionTokens
+		t.cost += s.Cost
+	}
+}
+
+// MeteredAIFilter records the token usage and cost of each provider request
+type MeteredAIFilter struct {
+	inner    AIFilterInterface
+	tracker  *UsageTracker
+	provider string
+	model    string
+	price    config.AIPrice
+}
+
+// NewMeteredAIFilter wraps the filter of one provider, pricing its usage with price
+func NewMeteredAIFilter(inner AIFilterInterface, tracker *UsageTracker, provider, model string, price config.AIPrice) *MeteredAIFilter {
+	return &MeteredAIFilter{
+		inner:    inner,
+		tracker:  tracker,
+		provider: provider,
+		model:    model,
+		price:    price,
+	}
+}
+
+// Filter calls the wrapped filter and records the usage it reports
+func (f *MeteredAIFilter) Filter(content string, prompt string) (string, error) {
+	reporter, ok := f.inner.(usageReporter)
+	if !ok {
+		return f.inner.Filter(content, prompt)
+	}
+
+	result, usage, err := reporter.filterWithUsage(content, prompt)
+	if usage.PromptTokens+usage.CompletionTokens > 0 {
+		cost := f.price.Cost(usage.PromptTokens, usage.CompletionTokens)
+		metrics.AddAICost(f.provider, f.model, cost)
+		f.tracker.Record(&database.AIUsage{
+			Provider:         f.provider,
+			Model:            f.model,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			Cost:             cost,
+			CreatedAt:        time.Now().UTC(),
+		})
+	}
+	return result, err
+}
+
+// IsValidResult delegates to the wrapped filter
+func (f *MeteredAIFilter) IsValidResult(result string) bool {
+	return f.inner.IsValidResult(result)
+}
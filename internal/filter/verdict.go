@@ -0,0 +1,132 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Structured Output Verdicts"
+//   Timestamp: "2025-12-13T10:20:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Cutting replies at the first \"END\" and comparing with \"FALSE\" broke on prose and words like WEEKEND"
+//   Principle_Applied: "Aether-Engineering-DRY, Robustness Principle"
+//   Quality_Check: "JSON verdicts parsed into a typed result; text replies still accepted by both providers"
+// }}
+
+package filter
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+)
+
+// verdictSchema is the JSON schema of the verdict requested in JSON output mode
+var verdictSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"relevant":      map[string]interface{}{"type": "boolean"},
+		"summary":       map[string]interface{}{"type": "string"},
+		"cheapest_plan": map[string]interface{}{"type": "string"},
+		"coupon":        map[string]interface{}{"type": "string"},
+		"confidence":    map[string]interface{}{"type": "number"},
+	},
+	"required":             []string{"relevant", "summary", "cheapest_plan", "coupon", "confidence"},
+	"additionalProperties": false,
+}
+
+// verdictInstructions is appended to the prompt in JSON output mode so that
+// models without schema support still answer in the expected shape
+const verdictInstructions = `
+
+只输出一个 JSON 对象，不要输出其他任何内容。字段：
+- relevant: 布尔值，内容是否值得通知（对应原先的非 FALSE）
+- summary: 字符串，按上面的要求给出的摘要或翻译
+- cheapest_plan: 字符串，最低价套餐的价格和配置，没有则为空字符串
+- coupon: 字符串，优惠码，没有则为空字符串
+- confidence: 0 到 1 之间的数字，判断的把握程度`
+
+var (
+	// endMarkerRe matches the END marker of the text protocol as a whole word
+	endMarkerRe = regexp.MustCompile(`\bEND\b`)
+	// rejectRe matches a text reply that starts with FALSE, possibly followed by prose
+	rejectRe = regexp.MustCompile(`(?i)^[\s"'*` + "`" + `]*FALSE\b`)
+)
+
+// verdictPrompt returns the system prompt for the output mode
+func verdictPrompt(prompt, outputMode string) string {
+	if outputMode != config.AIOutputJSON {
+		return prompt
+	}
+	return prompt + verdictInstructions
+}
+
+// normalizeResult turns a raw model reply into the result returned by Filter.
+// A JSON verdict is re-encoded compactly; anything else goes through the text
+// protocol, so replies without JSON still work in JSON mode.
+func normalizeResult(reply string) string {
+	if verdict := ParseVerdict(reply); verdict != nil {
+		data, err := json.Marshal(verdict)
+		if err == nil {
+			return string(data)
+		}
+	}
+
+	// Text protocol: the reply ends at the last standalone END marker
+	if locs := endMarkerRe.FindAllStringIndex(reply, -1); len(locs) > 0 {
+		reply = reply[:locs[len(locs)-1][0]]
+	}
+	return strings.TrimSpace(reply)
+}
+
+// ParseVerdict parses a JSON verdict from an AI result. Code fences and text
+// around the object are ignored. It returns nil when the result is not a
+// verdict, i.e. a text protocol reply.
+func ParseVerdict(result string) *database.AIVerdict {
+	start := strings.Index(result, "{")
+	end := strings.LastIndex(result, "}")
+	if start < 0 || end < start {
+		return nil
+	}
+
+	var raw struct {
+		Relevant     *bool   `json:"relevant"`
+		Summary      string  `json:"summary"`
+		CheapestPlan string  `json:"cheapest_plan"`
+		Coupon       string  `json:"coupon"`
+		Confidence   float64 `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(result[start:end+1]), &raw); err != nil || raw.Relevant == nil {
+		return nil
+	}
+
+	verdict := &database.AIVerdict{
+		Relevant:     *raw.Relevant,
+		Summary:      strings.TrimSpace(raw.Summary),
+		CheapestPlan: strings.TrimSpace(raw.CheapestPlan),
+		Coupon:       strings.TrimSpace(raw.Coupon),
+		Confidence:   raw.Confidence,
+	}
+	if verdict.Confidence < 0 {
+		verdict.Confidence = 0
+	} else if verdict.Confidence > 1 {
+		verdict.Confidence = 1
+	}
+	return verdict
+}
+
+// isRelevant reports whether a result accepts the content: the relevant flag
+// of a JSON verdict, or any text reply that does not start with FALSE
+func isRelevant(result string) bool {
+	if verdict := ParseVerdict(result); verdict != nil {
+		return verdict.Relevant
+	}
+	return !rejectRe.MatchString(result)
+}
+
+// Summary returns the text shown in notifications for an AI result: the
+// verdict summary in JSON mode, otherwise the result itself
+func Summary(result string) string {
+	if verdict := ParseVerdict(result); verdict != nil {
+		return verdict.Summary
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Prometheus Metrics"
+//   Timestamp: "2025-12-08T11:10:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Identified fetch, filter, notify, AI and DB hot paths lacking observability"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "Low-cardinality labels, default registry, promhttp handler"
+// }}
+
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "let_monitor"
+
+var (
+	// FetchesTotal counts HTTP fetches by kind ("rss" or "page"), host and status
+	FetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetches_total",
+		Help:      "HTTP fetches of RSS feeds and forum pages by host and status.",
+	}, []string{"kind", "host", "status"})
+
+	// FetchDuration observes HTTP fetch latency
+	FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_duration_seconds",
+		Help:      "Latency of HTTP fetches of RSS feeds and forum pages.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"kind", "host"})
+
+	// DiscoveredTotal counts new threads and comments stored in the database
+	DiscoveredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discovered_total",
+		Help:      "New threads and comments discovered.",
+	}, []string{"kind", "domain"})
+
+	// FilterDecisionsTotal counts filter outcomes, e.g. keyword pass/fail or AI accept/reject/error
+	FilterDecisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filter_decisions_total",
+		Help:      "Filter decisions by filter, item kind and outcome.",
+	}, []string{"filter", "kind", "decision"})
+
+	// NotificationsTotal counts notification deliveries per channel
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notification deliveries per channel and result.",
+	}, []string{"channel", "result"})
+
+	// AIRequestDuration observes AI provider latency
+	AIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "Latency of AI filter requests.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"provider", "model", "result"})
+
+	// AITokensTotal counts AI tokens by type ("prompt" or "completion")
+	AITokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens_total",
+		Help:      "AI tokens consumed by provider, model and type.",
+	}, []string{"provider", "model", "type"})
+
+	// AICostTotal sums the estimated cost of AI requests, see config ai_pricing
+	AICostTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_cost_usd_total",
+		Help:      "Estimated AI cost in USD by provider and model.",
+	}, []string{"provider", "model"})
+
+	// AICacheLookupsTotal counts AI result cache lookups by result ("hit" or "miss")
+	AICacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_cache_lookups_total",
+		Help:      "AI result cache lookups by provider, model and result.",
+	}, []string{"provider", "model", "result"})
+
+	// AICircuitOpen is 1 from the moment the circuit breaker of an AI provider
+	// opens until the provider answers again
+	AICircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ai_circuit_open",
+		Help:      "Whether the circuit breaker of an AI provider is open.",
+	}, []string{"provider"})
+
+	// DBOperationDuration observes database operation latency
+	DBOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_operation_duration_seconds",
+		Help:      "Latency of database operations.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"operation", "result"})
+)
+
+// Handler returns the HTTP handler serving the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveFetch records an HTTP fetch. statusCode is ignored when err is set.
+func ObserveFetch(kind, rawURL string, statusCode int, err error, started time.Time) {
+	host := rawURL
+	if parsed, parseErr := url.Parse(rawURL); parseErr == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(statusCode)
+	}
+
+	FetchesTotal.WithLabelValues(kind, host, status).Inc()
+	FetchDuration.WithLabelValues(kind, host).Observe(time.Since(started).Seconds())
+}
+
+// ObserveDiscovered records a newly stored thread or comment
+func ObserveDiscovered(kind, domain string) {
+	DiscoveredTotal.WithLabelValues(kind, domain).Inc()
+}
+
+// ObserveFilter records a filter decision
+func ObserveFilter(filter, kind, decision string) {
+	FilterDecisionsTotal.WithLabelValues(filter, kind, decision).Inc()
+}
+
+// ObserveNotification records a notification delivery attempt
+func ObserveNotification(channel string, err error) {
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+	NotificationsTotal.WithLabelValues(channel, result).Inc()
+}
+
+// ObserveAIRequest records the latency of an AI request
+func ObserveAIRequest(provider, model string, err error, started time.Time) {
+	AIRequestDuration.WithLabelValues(provider, model, result(err)).Observe(time.Since(started).Seconds())
+}
+
+// AddAITokens records AI token usage
+func AddAITokens(provider, model string, promptTokens, completionTokens int) {
+	if promptTokens > 0 {
+		AITokensTotal.WithLabelValues(provider, model, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		AITokensTotal.WithLabelValues(provider, model, "completion").Add(float64(completionTokens))
+	}
+}
+
+// AddAICost records the estimated cost of an AI request
+func AddAICost(provider, model string, cost float64) {
+	if cost > 0 {
+		AICostTotal.WithLabelValues(provider, model).Add(cost)
+	}
+}
+
+// ObserveAICache records an AI result cache lookup
+func ObserveAICache(provider, model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	AICacheLookupsTotal.WithLabelValues(provider, model, result).Inc()
+}
+
+// SetAICircuitOpen records the circuit breaker state of an AI provider
+func SetAICircuitOpen(provider string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	AICircuitOpen.WithLabelValues(provider).Set(value)
+}
+
+// ObserveDB records the latency of a database operation
+func ObserveDB(operation string, err error, started time.Time) {
+	DBOperationDuration.WithLabelValues(operation, result(err)).Observe(time.Since(started).Seconds())
+}
+
+// result maps an error to a "success"/"error" label value
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,182 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Thread Keyword Filtering And Filter Modes"
+//   Timestamp: "2025-12-10T09:15:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Keyword filter only covered comments, threads relied on AI alone"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, DRY"
+//   Quality_Check: "Threads and comments share one filter pipeline honoring filter_mode"
+// }}
+
+package monitor
+
+import (
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter"
+	"github.com/imhuimie/let-monitor-go/internal/filter/expr"
+	"github.com/imhuimie/let-monitor-go/internal/filter/prompt"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// filterSet holds the filters built from one config snapshot. A nil filter is disabled.
+type filterSet struct {
+	threadKeywords  *filter.KeywordFilter
+	commentKeywords *filter.KeywordFilter
+	ai              filter.AIFilterInterface
+	mode            string
+	failurePolicy   string // what to do when the AI filter fails, see config.AIFailureNotify
+	threadPrompt    *prompt.Template
+	commentPrompt   *prompt.Template
+
+	// Daily AI budgets; once reached only the keyword filter runs
+	usage       *filter.UsageTracker
+	tokenBudget int64
+	costBudget  float64
+}
+
+// newFilterSet builds the filters enabled in cfg; AI results are cached in db,
+// AI usage is recorded in usage and provider circuit breakers are kept in breakers
+func newFilterSet(cfg *config.Config, db database.Database, usage *filter.UsageTracker, breakers *filter.CircuitBreakers) filterSet {
+	fs := filterSet{
+		mode:          cfg.FilterMode,
+		failurePolicy: cfg.AIFailurePolicy,
+		usage:         usage,
+		tokenBudget:   cfg.AIDailyTokenBudget,
+		costBudget:    cfg.AIDailyCostBudget,
+		threadPrompt:  newPromptTemplate("thread_prompt", cfg.ThreadPrompt),
+		commentPrompt: newPromptTemplate("comment_prompt", cfg.CommentPrompt),
+	}
+
+	if cfg.UseKeywordsFilter {
+		fs.threadKeywords = newKeywordFilter(cfg.ThreadKeywordsRule)
+		fs.commentKeywords = newKeywordFilter(cfg.CommentKeywordRule())
+	}
+
+	if cfg.UseAIFilter {
+		aiFilter, err := filter.NewCachedAIFilterFromConfig(cfg, db, usage, breakers)
+		if err != nil {
+			log.Warnf("创建 AI 过滤器失败: %v，AI 过滤将被禁用", err)
+		} else {
+			fs.ai = aiFilter
+		}
+	}
+
+	return fs
+}
+
+// newKeywordFilter compiles a keyword rule; an empty rule disables the filter.
+// The config rejects invalid rules, but should one get here it matches
+// nothing rather than letting everything through.
+func newKeywordFilter(rule string) *filter.KeywordFilter {
+	if rule == "" {
+		return nil
+	}
+	keywords, err := filter.NewKeywordFilter(rule)
+	if err != nil {
+		log.Errorf("关键词规则无效: %v，将不匹配任何内容", err)
+		keywords, _ = filter.NewKeywordFilter("")
+	}
+	return keywords
+}
+
+// newPromptTemplate parses a prompt template; an invalid template is sent as it is
+func newPromptTemplate(name, text string) *prompt.Template {
+	tmpl, err := prompt.Parse(text)
+	if err != nil {
+		log.Warnf("%s 模板无效: %v，将按原文发送", name, err)
+		return prompt.Literal(text)
+	}
+	return tmpl
+}
+
+// renderPrompt renders a prompt template, falling back to its unrendered text
+func renderPrompt(tmpl *prompt.Template, data prompt.Data) string {
+	rendered, err := tmpl.Render(data)
+	if err != nil {
+		log.Warnf("%v，将按原文发送", err)
+		return tmpl.Text()
+	}
+	return rendered
+}
+
+// apply runs the keyword filter on doc and the AI filter on aiContent,
+// combined according to the filter mode, and records the outcome in decision.
+// It returns an empty string when the item passes, otherwise the status
+// explaining why it was rejected.
+//
+// In "and" mode both enabled filters must pass. In "or" mode a keyword match
+// is enough and skips the AI call; otherwise the AI filter decides. When no
+// AI provider answers, the failure policy decides: notify unfiltered, hold
+// for re-evaluation or drop. Once the daily AI budget is used up the AI
+// filter is skipped as if it were disabled.
+func (fs filterSet) apply(kind string, keywords *filter.KeywordFilter, doc expr.Document, aiContent, prompt string, decision *database.Decision) string {
+	aiFilter := fs.ai
+	if aiFilter != nil && fs.usage != nil && fs.usage.OverBudget(fs.tokenBudget, fs.costBudget) {
+		aiFilter = nil
+		decision.AISkipped = database.AISkippedBudget
+		metrics.ObserveFilter("ai", kind, "skipped")
+	}
+
+	keywordMatched := false
+	if keywords != nil {
+		decision.Filters = append(decision.Filters, "keyword")
+		rule, ok := keywords.MatchDocument(doc)
+		if ok {
+			decision.KeywordRule = rule
+			keywordMatched = true
+			metrics.ObserveFilter("keyword", kind, "pass")
+		} else {
+			metrics.ObserveFilter("keyword", kind, "fail")
+			if fs.mode != config.FilterModeOr || aiFilter == nil {
+				return database.StatusKeywordFiltered
+			}
+		}
+	}
+
+	if aiFilter == nil || (fs.mode == config.FilterModeOr && keywordMatched) {
+		return ""
+	}
+
+	accepted, err := applyAIFilter(aiFilter, kind, aiContent, prompt, decision)
+	if err != nil {
+		switch fs.failurePolicy {
+		case config.AIFailureHold:
+			return database.StatusAIHeld
+		case config.AIFailureDrop:
+			return database.StatusAIFailed
+		default:
+			return ""
+		}
+	}
+	if !accepted {
+		return database.StatusAIRejected
+	}
+	return ""
+}
+
+// applyAIFilter runs the AI filter and records its output in decision. It
+// returns the error when no AI provider answered.
+func applyAIFilter(aiFilter filter.AIFilterInterface, kind, content, prompt string, decision *database.Decision) (bool, error) {
+	decision.Filters = append(decision.Filters, "ai")
+
+	result, err := aiFilter.Filter(content, prompt)
+	if err != nil {
+		log.Warnf("AI 过滤失败: %v", err)
+		metrics.ObserveFilter("ai", kind, "error")
+		decision.AIError = err.Error()
+		return false, err
+	}
+
+	accepted := aiFilter.IsValidResult(result)
+	decision.AIResult = result
+	decision.AIAccepted = &accepted
+	decision.AIVerdict = filter.ParseVerdict(result)
+	if accepted {
+		metrics.ObserveFilter("ai", kind, "accept")
+	} else {
+		metrics.ObserveFilter("ai", kind, "reject")
+	}
+	return accepted, nil
+}
@@ -0,0 +1,157 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Failure Policy Re-evaluation"
+//   Timestamp: "2025-12-15T10:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "With ai_failure_policy=hold, items no AI provider could judge must be filtered once one recovers"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Retry Pattern"
+//   Quality_Check: "Held items re-run the full filter pipeline; items past the 24 hour window expire as too_old"
+// }}
+
+package monitor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	heldPollInterval = time.Minute
+	heldBatchSize    = 50
+	heldMaxAge       = 24 * time.Hour
+)
+
+// heldLoop periodically re-evaluates items held because no AI provider
+// answered. Like scheduled checks it does nothing while paused.
+func (m *ForumMonitor) heldLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(heldPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if m.IsPaused() {
+				continue
+			}
+			m.reevaluateHeldThreads()
+			m.reevaluateHeldComments()
+		}
+	}
+}
+
+// reevaluateHeldThreads runs the filters again on all held threads, oldest
+// first and heldBatchSize at a time. Threads held again are still listed,
+// so the offset skips them; the others drop out of the listing.
+func (m *ForumMonitor) reevaluateHeldThreads() {
+	offset := 0
+	for m.ctx.Err() == nil && !m.IsPaused() {
+		threads, _, err := m.db.ListThreads(database.ThreadQuery{
+			Status: database.StatusAIHeld,
+			Oldest: true,
+			Offset: offset,
+			Limit:  heldBatchSize,
+		})
+		if err != nil {
+			log.Warnf("查询待重新判断的线程失败: %v", err)
+			return
+		}
+
+		for _, thread := range threads {
+			if m.ctx.Err() != nil {
+				return
+			}
+			m.reevaluateHeldThread(thread)
+			if thread.Status == database.StatusAIHeld {
+				offset++
+			}
+		}
+		if len(threads) < heldBatchSize {
+			return
+		}
+	}
+}
+
+// reevaluateHeldThread runs the filters again on a held thread, or expires it
+func (m *ForumMonitor) reevaluateHeldThread(thread *database.Thread) {
+	thread.Decision = reevaluationDecision(thread.Decision)
+	if time.Since(thread.PubDate) > heldMaxAge {
+		log.Debugf("线程等待 AI 判断超时，不再通知: %s", thread.Title)
+		m.setThreadStatus(thread, database.StatusTooOld, "")
+		return
+	}
+
+	log.Debugf("重新判断线程: %s", thread.Title)
+	m.notifyThread(thread)
+}
+
+// reevaluateHeldComments runs the filters again on all held comments, oldest
+// first and heldBatchSize at a time, like reevaluateHeldThreads
+func (m *ForumMonitor) reevaluateHeldComments() {
+	offset := 0
+	for m.ctx.Err() == nil && !m.IsPaused() {
+		comments, _, err := m.db.ListComments(database.CommentQuery{
+			Status: database.StatusAIHeld,
+			Oldest: true,
+			Offset: offset,
+			Limit:  heldBatchSize,
+		})
+		if err != nil {
+			log.Warnf("查询待重新判断的评论失败: %v", err)
+			return
+		}
+
+		for _, comment := range comments {
+			if m.ctx.Err() != nil {
+				return
+			}
+			m.reevaluateHeldComment(comment)
+			if comment.Status == database.StatusAIHeld {
+				offset++
+			}
+		}
+		if len(comments) < heldBatchSize {
+			return
+		}
+	}
+}
+
+// reevaluateHeldComment runs the filters again on a held comment, or expires it
+func (m *ForumMonitor) reevaluateHeldComment(comment *database.Comment) {
+	comment.Decision = reevaluationDecision(comment.Decision)
+	if time.Since(comment.CreatedAt) > heldMaxAge {
+		log.Debugf("评论等待 AI 判断超时，不再通知: %s", comment.URL)
+		m.setCommentStatus(comment, database.StatusTooOld, "")
+		return
+	}
+
+	thread, err := m.db.FindThread(comment.ThreadURL)
+	if err != nil || thread == nil {
+		log.Warnf("查询评论所属线程失败: %s", comment.ThreadURL)
+		return
+	}
+
+	log.Debugf("重新判断评论: %s", comment.URL)
+	m.notifyComment(thread, comment)
+}
+
+// reevaluationDecision returns a fresh decision for filtering an item again,
+// keeping the comment filter that selected it in the first place
+func reevaluationDecision(previous *database.Decision) *database.Decision {
+	decision := &database.Decision{}
+	if previous == nil {
+		return decision
+	}
+	for _, f := range previous.Filters {
+		if strings.HasPrefix(f, "comment_filter:") {
+			decision.Filters = append(decision.Filters, f)
+		}
+	}
+	return decision
+}
@@ -14,6 +14,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/imhuimie/let-monitor-go/internal/config"
@@ -27,13 +28,27 @@ import (
 type ForumMonitor struct {
 	config    *config.Manager
 	db        database.Database
-	notifier  notifier.Notifier
+	notifier  *notifier.FanoutNotifier
 	scraper   *Scraper
 	rssParser *RSSParser
 
 	// Filters
-	keywordFilter *filter.KeywordFilter
-	aiFilter      filter.AIFilterInterface
+	filters  filterSet
+	usage    *filter.UsageTracker    // AI usage of the current day, kept across reloads
+	breakers *filter.CircuitBreakers // AI provider circuit breakers, kept across reloads
+
+	// Scheduling
+	sched       *scheduler
+	jobs        chan source
+	workerStops []chan struct{} // one per worker, closed to retire it; guarded by dispatchMu
+	inFlight    atomic.Int32    // sources queued or running, at most one per worker
+	limiter     *HostLimiter
+	threadLocks map[string]*threadLock // by thread link, removed when unused
+	threadMu    sync.Mutex
+	dispatchMu  sync.Mutex
+	paused      atomic.Bool
+	resumed     atomic.Int64 // unix nanoseconds of the last Resume
+	startedAt   time.Time
 
 	// Control
 	ctx    context.Context
@@ -50,54 +65,52 @@ func NewForumMonitor(cfgMgr *config.Manager, db database.Database) (*ForumMonito
 	}
 
 	// Create notifier
-	ntf, err := notifier.NewNotifier(cfg)
+	ntf, err := notifier.NewFanoutNotifier(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("创建通知器失败: %w", err)
 	}
 
-	// Create filters
-	var keywordFilter *filter.KeywordFilter
-	if cfg.UseKeywordsFilter {
-		keywordFilter = filter.NewKeywordFilter(cfg.KeywordsRule)
-	}
-
-	var aiFilter filter.AIFilterInterface
-	if cfg.UseAIFilter {
-		var err error
-		aiFilter, err = filter.NewAIFilterFromConfig(cfg)
-		if err != nil {
-			log.Warnf("创建 AI 过滤器失败: %v，AI 过滤将被禁用", err)
-			aiFilter = nil
-		}
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
+	limiter := NewHostLimiter(ctx, time.Duration(cfg.HostRateLimit)*time.Millisecond)
+	usage := filter.NewUsageTracker(db)
+	breakers := filter.NewCircuitBreakers()
 
 	return &ForumMonitor{
-		config:        cfgMgr,
-		db:            db,
-		notifier:      ntf,
-		scraper:       NewScraper(),
-		rssParser:     NewRSSParser(),
-		keywordFilter: keywordFilter,
-		aiFilter:      aiFilter,
-		ctx:           ctx,
-		cancel:        cancel,
+		config:      cfgMgr,
+		db:          db,
+		notifier:    ntf,
+		scraper:     NewScraper(limiter),
+		rssParser:   NewRSSParser(limiter),
+		filters:     newFilterSet(cfg, db, usage, breakers),
+		usage:       usage,
+		breakers:    breakers,
+		sched:       newScheduler(cfg),
+		jobs:        make(chan source),
+		limiter:     limiter,
+		threadLocks: make(map[string]*threadLock),
+		ctx:         ctx,
+		cancel:      cancel,
 	}, nil
 }
 
 // Start starts the monitoring loop
 func (m *ForumMonitor) Start() {
 	log.Info("开始监控...")
+	m.startedAt = time.Now()
+
+	m.resizeWorkers(m.config.Get().MaxWorkers)
 
-	m.wg.Add(1)
+	m.wg.Add(3)
 	go m.monitorLoop()
+	go m.outboxLoop()
+	go m.heldLoop()
 }
 
 // Stop stops the monitoring loop gracefully
 func (m *ForumMonitor) Stop() {
 	log.Info("停止监控...")
 	m.cancel()
+	m.currentNotifier().Close()
 	m.wg.Wait()
 	log.Info("监控已停止")
 }
@@ -114,107 +127,69 @@ func (m *ForumMonitor) Reload() error {
 	cfg := m.config.Get()
 
 	// Recreate notifier
-	ntf, err := notifier.NewNotifier(cfg)
+	ntf, err := notifier.NewFanoutNotifier(cfg)
 	if err != nil {
 		return fmt.Errorf("重新创建通知器失败: %w", err)
 	}
+	// Sends still waiting on the old notifier fail and go to the outbox
+	m.notifier.Close()
 	m.notifier = ntf
 
 	// Recreate filters
-	if cfg.UseKeywordsFilter {
-		m.keywordFilter = filter.NewKeywordFilter(cfg.KeywordsRule)
-	} else {
-		m.keywordFilter = nil
-	}
+	m.filters = newFilterSet(cfg, m.db, m.usage, m.breakers)
 
-	if cfg.UseAIFilter {
-		aiFilter, err := filter.NewAIFilterFromConfig(cfg)
-		if err != nil {
-			log.Warnf("创建 AI 过滤器失败: %v，AI 过滤将被禁用", err)
-			m.aiFilter = nil
-		} else {
-			m.aiFilter = aiFilter
-		}
-	} else {
-		m.aiFilter = nil
-	}
+	// Apply scheduling changes to the running scheduler
+	m.sched.sync(cfg)
+	m.limiter.SetInterval(time.Duration(cfg.HostRateLimit) * time.Millisecond)
+	m.resizeWorkers(cfg.MaxWorkers)
 
 	log.Info("配置重新加载成功")
 	return nil
 }
 
-// monitorLoop is the main monitoring loop
-func (m *ForumMonitor) monitorLoop() {
-	defer m.wg.Done()
-
-	cfg := m.config.Get()
-	ticker := time.NewTicker(time.Duration(cfg.Frequency) * time.Second)
-	defer ticker.Stop()
-
-	// Run immediately on start
-	m.runCheck()
-
-	for {
-		select {
-		case <-m.ctx.Done():
-			return
-		case <-ticker.C:
-			m.runCheck()
-		}
-	}
+// currentNotifier returns the notifier, which may be replaced by Reload
+func (m *ForumMonitor) currentNotifier() *notifier.FanoutNotifier {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.notifier
 }
 
-// runCheck performs one complete check cycle
-func (m *ForumMonitor) runCheck() {
-	cfg := m.config.Get()
-
-	log.Infof("[%s] 开始检查...", time.Now().Format("2006-01-02 15:04:05"))
-
-	// Check extra URLs first
-	if len(cfg.ExtraURLs) > 0 {
-		m.checkExtraURLs(cfg.ExtraURLs)
-	}
-
-	// Check RSS feeds if not only_extra mode
-	if !cfg.OnlyExtra && len(cfg.URLs) > 0 {
-		m.checkRSSFeeds(cfg.URLs)
-	}
+// currentFilters returns the filters, which may be replaced by Reload
+func (m *ForumMonitor) currentFilters() filterSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.filters
+}
 
-	freq := cfg.Frequency
-	log.Infof("[%s] 检查完成，休眠 %d 秒...", time.Now().Format("2006-01-02 15:04:05"), freq)
+// threadLock is the lock of one thread, shared by the workers waiting for it
+type threadLock struct {
+	mu   sync.Mutex
+	refs int // holders and waiters, guarded by ForumMonitor.threadMu
 }
 
-// checkRSSFeeds checks all RSS feeds
-func (m *ForumMonitor) checkRSSFeeds(urls []string) {
-	for _, url := range urls {
-		if err := m.processRSSFeed(url); err != nil {
-			log.Warnf("处理 RSS feed 失败 %s: %v", url, err)
-		}
-		time.Sleep(1 * time.Second) // Rate limiting
+// lockThread serializes processing of a single thread across workers and
+// returns the matching unlock function. The lock is dropped once no worker
+// holds or waits for it, so locks do not accumulate for every thread seen.
+func (m *ForumMonitor) lockThread(link string) func() {
+	m.threadMu.Lock()
+	lock := m.threadLocks[link]
+	if lock == nil {
+		lock = &threadLock{}
+		m.threadLocks[link] = lock
 	}
-}
+	lock.refs++
+	m.threadMu.Unlock()
 
-// checkExtraURLs checks extra URLs directly
-func (m *ForumMonitor) checkExtraURLs(urls []string) {
-	for _, url := range urls {
-		// Check if thread already exists
-		thread, err := m.db.FindThread(url)
-		if err != nil {
-			log.Warnf("查询线程失败 %s: %v", url, err)
-			continue
-		}
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
 
-		if thread != nil {
-			// Thread exists, fetch comments
-			m.fetchComments(thread)
-		} else {
-			// Thread doesn't exist, fetch and process
-			if err := m.fetchThreadPage(url); err != nil {
-				log.Warnf("抓取线程页面失败 %s: %v", url, err)
-			}
+		m.threadMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(m.threadLocks, link)
 		}
-
-		time.Sleep(2 * time.Second) // Rate limiting
+		m.threadMu.Unlock()
 	}
 }
 
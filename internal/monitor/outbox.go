@@ -0,0 +1,246 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Notification Outbox Retry Worker"
+//   Timestamp: "2025-12-03T09:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Failed notifications were only logged and then lost"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Transactional Outbox"
+//   Quality_Check: "Exponential backoff with dead-letter state after max attempts"
+// }}
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	"github.com/imhuimie/let-monitor-go/internal/notifier"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	outboxPollInterval = 15 * time.Second
+	outboxBatchSize    = 50
+	outboxMaxAttempts  = 10
+	outboxBaseBackoff  = 30 * time.Second
+	outboxMaxBackoff   = time.Hour
+)
+
+// outboxBackoff returns the delay before the next attempt after the given number of attempts
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return delay
+}
+
+// enqueueFailedDeliveries stores failed deliveries in the outbox for retry.
+// A delivery that reached some targets of its channel is retried for the
+// other targets only.
+func (m *ForumMonitor) enqueueFailedDeliveries(kind, threadLink, commentID, aiDescription string, deliveries []notifier.Delivery) {
+	now := time.Now().UTC()
+
+	for _, d := range deliveries {
+		if d.Err == nil {
+			continue
+		}
+
+		log.Warnf("发送通知失败 [%s]: %v，已加入重试队列", d.Channel, d.Err)
+
+		entry := &database.OutboxEntry{
+			Channel:       d.Channel,
+			Kind:          kind,
+			ThreadLink:    threadLink,
+			CommentID:     commentID,
+			AIDescription: aiDescription,
+			Targets:       failedTargets(d.Err, nil),
+			Status:        database.OutboxPending,
+			Attempts:      1,
+			LastError:     d.Err.Error(),
+			NextAttemptAt: now.Add(outboxBackoff(1)),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := m.db.InsertOutbox(entry); err != nil {
+			log.Errorf("写入通知重试队列失败 [%s]: %v", d.Channel, err)
+		}
+	}
+}
+
+// failedTargets returns the targets a delivery still has to reach: those
+// listed by a *notifier.TargetError, otherwise the targets it was sent to
+func failedTargets(err error, targets []string) []string {
+	var targetErr *notifier.TargetError
+	if errors.As(err, &targetErr) {
+		return targetErr.Failed
+	}
+	return targets
+}
+
+// outboxLoop periodically retries pending outbox entries
+func (m *ForumMonitor) outboxLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.processOutbox()
+		}
+	}
+}
+
+// processOutbox retries all outbox entries that are due
+func (m *ForumMonitor) processOutbox() {
+	entries, err := m.db.ListDueOutbox(time.Now().UTC(), outboxBatchSize)
+	if err != nil {
+		log.Warnf("查询通知重试队列失败: %v", err)
+		return
+	}
+
+	ntf := m.currentNotifier()
+	for _, entry := range entries {
+		select {
+		case <-m.ctx.Done():
+			return
+		default:
+		}
+
+		err := m.retryOutboxEntry(ntf, entry)
+		metrics.ObserveNotification(entry.Channel, err)
+		now := time.Now().UTC()
+		entry.UpdatedAt = now
+		entry.Targets = failedTargets(err, entry.Targets)
+
+		switch {
+		case err == nil:
+			entry.Status = database.OutboxSent
+			entry.LastError = ""
+			log.Infof("重试通知成功 [%s]: %s", entry.Channel, entry.ThreadLink)
+			m.recordRetryOutcome(entry, database.DeliverySent)
+		case entry.Attempts+1 >= outboxMaxAttempts:
+			entry.Attempts++
+			entry.Status = database.OutboxDead
+			entry.LastError = err.Error()
+			log.Errorf("通知重试 %d 次后放弃 [%s]: %v", entry.Attempts, entry.Channel, err)
+			m.recordRetryOutcome(entry, database.DeliveryDead)
+		default:
+			entry.Attempts++
+			entry.LastError = err.Error()
+			entry.NextAttemptAt = now.Add(outboxBackoff(entry.Attempts))
+			log.Warnf("重试通知失败 [%s] (第 %d 次): %v", entry.Channel, entry.Attempts, err)
+		}
+
+		if err := m.db.UpdateOutbox(entry); err != nil {
+			log.Warnf("更新通知重试队列失败: %v", err)
+		}
+	}
+}
+
+// recordRetryOutcome updates the delivery record of the thread or comment an
+// outbox entry belongs to, marking it notified once any channel succeeds
+func (m *ForumMonitor) recordRetryOutcome(entry *database.OutboxEntry, deliveryStatus string) {
+	var err error
+	switch entry.Kind {
+	case "thread":
+		var thread *database.Thread
+		if thread, err = m.db.FindThread(entry.ThreadLink); err == nil && thread != nil {
+			thread.Decision = withDeliveryRecord(thread.Decision, entry, deliveryStatus)
+			if deliveryStatus == database.DeliverySent {
+				thread.Status = database.StatusNotified
+			}
+			err = m.db.UpdateThreadStatus(thread.Link, thread.Status, thread.AISummary, thread.Decision)
+		}
+	case "comment":
+		var comment *database.Comment
+		if comment, err = m.db.FindComment(entry.CommentID); err == nil && comment != nil {
+			comment.Decision = withDeliveryRecord(comment.Decision, entry, deliveryStatus)
+			if deliveryStatus == database.DeliverySent {
+				comment.Status = database.StatusNotified
+			}
+			err = m.db.UpdateCommentStatus(comment.CommentID, comment.Status, comment.AISummary, comment.Decision)
+		}
+	}
+	if err != nil {
+		log.Warnf("更新通知状态失败: %v", err)
+	}
+}
+
+// withDeliveryRecord sets the outcome of the entry's channel in decision,
+// creating the decision for records stored before decisions were tracked
+func withDeliveryRecord(decision *database.Decision, entry *database.OutboxEntry, deliveryStatus string) *database.Decision {
+	if decision == nil {
+		decision = &database.Decision{}
+	}
+
+	record := database.DeliveryRecord{
+		Channel: entry.Channel,
+		Status:  deliveryStatus,
+		Error:   entry.LastError,
+		At:      entry.UpdatedAt,
+	}
+
+	for i := range decision.Deliveries {
+		if decision.Deliveries[i].Channel == entry.Channel {
+			decision.Deliveries[i] = record
+			return decision
+		}
+	}
+	decision.Deliveries = append(decision.Deliveries, record)
+	return decision
+}
+
+// retryOutboxEntry resends a single outbox entry through its channel, to the
+// targets it missed when it lists any
+func (m *ForumMonitor) retryOutboxEntry(fanout *notifier.FanoutNotifier, entry *database.OutboxEntry) error {
+	ch := fanout.Channel(entry.Channel)
+	if ch == nil {
+		return fmt.Errorf("通知渠道不存在: %s", entry.Channel)
+	}
+
+	thread, err := m.db.FindThread(entry.ThreadLink)
+	if err != nil {
+		return fmt.Errorf("查询线程失败: %w", err)
+	}
+	if thread == nil {
+		return fmt.Errorf("线程不存在: %s", entry.ThreadLink)
+	}
+
+	ntf := ch.Notifier
+	if len(entry.Targets) > 0 {
+		targeted, ok := ntf.(notifier.TargetedNotifier)
+		if !ok {
+			return fmt.Errorf("通知渠道 %s 不支持按目标重试", entry.Channel)
+		}
+		if ntf, err = targeted.WithTargets(entry.Targets); err != nil {
+			return err
+		}
+	}
+
+	switch entry.Kind {
+	case "thread":
+		return ntf.SendThread(thread, entry.AIDescription)
+	case "comment":
+		comment, err := m.db.FindComment(entry.CommentID)
+		if err != nil {
+			return fmt.Errorf("查询评论失败: %w", err)
+		}
+		if comment == nil {
+			return fmt.Errorf("评论不存在: %s", entry.CommentID)
+		}
+		return ntf.SendComment(thread, comment, entry.AIDescription)
+	default:
+		return fmt.Errorf("未知的通知类型: %s", entry.Kind)
+	}
+}
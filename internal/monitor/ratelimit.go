@@ -0,0 +1,75 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Per-Host Rate Limiter"
+//   Timestamp: "2025-12-04T14:05:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Replaced fixed time.Sleep calls with host-scoped request pacing"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "Concurrent-safe slot reservation, cancellable waits"
+// }}
+
+package monitor
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HostLimiter enforces a minimum interval between requests to the same host
+type HostLimiter struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+// NewHostLimiter creates a limiter; waits are cancelled when ctx is done
+func NewHostLimiter(ctx context.Context, interval time.Duration) *HostLimiter {
+	return &HostLimiter{
+		ctx:      ctx,
+		interval: interval,
+		next:     make(map[string]time.Time),
+	}
+}
+
+// SetInterval changes the minimum interval between requests to one host
+func (l *HostLimiter) SetInterval(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = interval
+}
+
+// Wait blocks until a request to the host of rawURL is allowed
+func (l *HostLimiter) Wait(rawURL string) error {
+	host := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+
+	// Reserve the next free slot for this host
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
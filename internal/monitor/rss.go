@@ -17,33 +17,43 @@ import (
 	"time"
 
 	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
 	"github.com/mmcdole/gofeed"
 	log "github.com/sirupsen/logrus"
 )
 
 // RSSParser parses RSS feeds
 type RSSParser struct {
-	parser *gofeed.Parser
-	client *http.Client
+	parser  *gofeed.Parser
+	client  *http.Client
+	limiter *HostLimiter
 }
 
 // NewRSSParser creates a new RSS parser
-func NewRSSParser() *RSSParser {
+func NewRSSParser(limiter *HostLimiter) *RSSParser {
 	return &RSSParser{
 		parser: gofeed.NewParser(),
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		limiter: limiter,
 	}
 }
 
 // ParseURL parses an RSS feed URL
 func (r *RSSParser) ParseURL(url string) ([]*database.Thread, error) {
+	if err := r.limiter.Wait(url); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	resp, err := r.client.Get(url)
 	if err != nil {
+		metrics.ObserveFetch("rss", url, 0, err, start)
 		return nil, fmt.Errorf("获取 RSS feed 失败: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveFetch("rss", url, resp.StatusCode, nil, start)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("RSS feed 返回状态码 %d", resp.StatusCode)
@@ -164,9 +174,14 @@ func (m *ForumMonitor) processRSSFeed(url string) error {
 	}
 
 	for _, thread := range threads {
+		if m.ctx.Err() != nil {
+			break
+		}
+
+		unlock := m.lockThread(thread.Link)
 		m.handleThread(thread)
 		m.fetchComments(thread)
-		time.Sleep(500 * time.Millisecond) // Rate limiting
+		unlock()
 	}
 
 	return nil
@@ -0,0 +1,578 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Per-Source Concurrent Scheduler"
+//   Timestamp: "2025-12-04T14:20:00Z"
+//   Authoring_Role: "AR"
+//   Analysis_Performed: "Serial check loop let one slow forum delay every other source"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Worker Pool"
+//   Quality_Check: "Independent intervals, bounded concurrency, no duplicate runs per source"
+// }}
+
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/filter"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sourceRSS   = "rss"   // RSS feed listing new threads
+	sourceExtra = "extra" // single thread URL watched for comments
+)
+
+// source is a monitored URL with its own schedule
+type source struct {
+	URL          string
+	Kind         string
+	Interval     time.Duration
+	NextRun      time.Time
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastSuccess  time.Time
+	LastFailure  time.Time
+	LastError    string
+	running      bool
+	forced       bool // manually triggered, runs even while paused; if running, again once done
+}
+
+// scheduler tracks when each source is due. A check cycle starts when a source
+// is dispatched while no cycle is in progress and completes once every source
+// known at its start has finished a run.
+type scheduler struct {
+	mu      sync.Mutex
+	sources map[string]*source
+
+	cycleStart   time.Time
+	cyclePending map[string]bool
+	lastCycle    *CycleStatus
+}
+
+// SourceStatus describes the schedule and last outcome of one source
+type SourceStatus struct {
+	URL          string    `json:"url"`
+	Kind         string    `json:"kind"`
+	Interval     int       `json:"interval"` // in seconds
+	LastRun      time.Time `json:"last_run"`
+	LastDuration string    `json:"last_duration"`
+	LastSuccess  time.Time `json:"last_success"`
+	LastFailure  time.Time `json:"last_failure"`
+	LastError    string    `json:"last_error,omitempty"`
+	NextRun      time.Time `json:"next_run"`
+	Running      bool      `json:"running"`
+}
+
+// CycleStatus describes a completed check cycle over all sources
+type CycleStatus struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   string    `json:"duration"`
+}
+
+// newScheduler creates a scheduler with every source from the config due immediately
+func newScheduler(cfg *config.Config) *scheduler {
+	s := &scheduler{sources: make(map[string]*source)}
+	s.sync(cfg)
+	return s
+}
+
+// sync reconciles the sources with the config: new sources are due immediately,
+// removed ones are dropped and changed intervals apply from the last run
+func (s *scheduler) sync(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wanted := make(map[string]string)
+	for _, url := range cfg.ExtraURLs {
+		wanted[url] = sourceExtra
+	}
+	if !cfg.OnlyExtra {
+		for _, url := range cfg.URLs {
+			if _, exists := wanted[url]; !exists {
+				wanted[url] = sourceRSS
+			}
+		}
+	}
+
+	for url := range s.sources {
+		if _, ok := wanted[url]; !ok {
+			delete(s.sources, url)
+			s.completeCycleSource(url, now)
+		}
+	}
+
+	for url, kind := range wanted {
+		interval := cfg.SourceInterval(url)
+
+		src, exists := s.sources[url]
+		if !exists {
+			s.sources[url] = &source{
+				URL:      url,
+				Kind:     kind,
+				Interval: interval,
+				NextRun:  now,
+			}
+			continue
+		}
+
+		src.Kind = kind
+		if src.Interval != interval {
+			src.Interval = interval
+			if !src.LastRun.IsZero() {
+				src.NextRun = src.LastRun.Add(interval)
+				if src.NextRun.Before(now) {
+					src.NextRun = now
+				}
+			}
+		}
+	}
+}
+
+// status returns the schedule of every source, ordered by next run
+func (s *scheduler) status() []SourceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]SourceStatus, 0, len(s.sources))
+	for _, src := range s.sources {
+		statuses = append(statuses, SourceStatus{
+			URL:          src.URL,
+			Kind:         src.Kind,
+			Interval:     int(src.Interval / time.Second),
+			LastRun:      src.LastRun,
+			LastDuration: src.LastDuration.Round(time.Millisecond).String(),
+			LastSuccess:  src.LastSuccess,
+			LastFailure:  src.LastFailure,
+			LastError:    src.LastError,
+			NextRun:      src.NextRun,
+			Running:      src.running,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NextRun.Before(statuses[j].NextRun)
+	})
+	return statuses
+}
+
+// takeDue marks up to limit due sources as running and returns them, earliest first.
+// While paused only manually triggered sources are returned.
+func (s *scheduler) takeDue(now time.Time, limit int, paused bool) []source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*source
+	for _, src := range s.sources {
+		if src.running || src.NextRun.After(now) {
+			continue
+		}
+		if paused && !src.forced {
+			continue
+		}
+		due = append(due, src)
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextRun.Before(due[j].NextRun)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	if len(due) > 0 && s.cyclePending == nil {
+		s.cycleStart = now
+		s.cyclePending = make(map[string]bool, len(s.sources))
+		for url := range s.sources {
+			s.cyclePending[url] = true
+		}
+	}
+
+	taken := make([]source, 0, len(due))
+	for _, src := range due {
+		src.running = true
+		src.forced = false
+		taken = append(taken, *src)
+	}
+	return taken
+}
+
+// completeCycleSource marks a source as done for the current cycle and closes
+// the cycle once no source is pending. Callers must hold s.mu.
+func (s *scheduler) completeCycleSource(url string, now time.Time) {
+	if s.cyclePending == nil {
+		return
+	}
+
+	delete(s.cyclePending, url)
+	if len(s.cyclePending) > 0 {
+		return
+	}
+
+	s.lastCycle = &CycleStatus{
+		StartedAt:  s.cycleStart,
+		FinishedAt: now,
+		Duration:   now.Sub(s.cycleStart).Round(time.Millisecond).String(),
+	}
+	s.cyclePending = nil
+}
+
+// lastCompletedCycle returns the most recently completed cycle, or nil
+func (s *scheduler) lastCompletedCycle() *CycleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastCycle == nil {
+		return nil
+	}
+	cycle := *s.lastCycle
+	return &cycle
+}
+
+// maxInterval returns the longest interval of any source
+func (s *scheduler) maxInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var longest time.Duration
+	for _, src := range s.sources {
+		if src.Interval > longest {
+			longest = src.Interval
+		}
+	}
+	return longest
+}
+
+// trigger makes a source, or every source when url is empty, due immediately.
+// A source that is running is checked again as soon as its run finishes.
+func (s *scheduler) trigger(url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if url == "" {
+		for _, src := range s.sources {
+			src.force(now)
+		}
+		return nil
+	}
+
+	src, ok := s.sources[url]
+	if !ok {
+		return fmt.Errorf("未监控的来源: %s", url)
+	}
+	src.force(now)
+	return nil
+}
+
+// force marks a source as manually triggered; finish reschedules running sources
+func (src *source) force(now time.Time) {
+	src.forced = true
+	if !src.running {
+		src.NextRun = now
+	}
+}
+
+// active returns the URLs of the sources currently being checked
+func (s *scheduler) active() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var urls []string
+	for _, src := range s.sources {
+		if src.running {
+			urls = append(urls, src.URL)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// finish records the outcome of a run and schedules the next one
+func (s *scheduler) finish(url string, started time.Time, runErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	defer s.completeCycleSource(url, now)
+
+	src, ok := s.sources[url]
+	if !ok {
+		return
+	}
+
+	src.running = false
+	src.LastRun = started
+	src.LastDuration = now.Sub(started)
+	if runErr != nil {
+		src.LastFailure = now
+		src.LastError = runErr.Error()
+	} else {
+		src.LastSuccess = now
+		src.LastError = ""
+	}
+
+	// A manual trigger during the run is still pending and runs right away
+	src.NextRun = started.Add(src.Interval)
+	if src.forced || src.NextRun.Before(now) {
+		src.NextRun = now
+	}
+}
+
+// Schedule returns the effective schedule of every monitored source
+func (m *ForumMonitor) Schedule() []SourceStatus {
+	return m.sched.status()
+}
+
+// Pause stops scheduled checks until Resume is called; running checks finish normally
+func (m *ForumMonitor) Pause() {
+	m.paused.Store(true)
+	log.Info("监控已暂停")
+}
+
+// Resume restarts scheduled checks after Pause
+func (m *ForumMonitor) Resume() {
+	if m.paused.Swap(false) {
+		m.resumed.Store(time.Now().UnixNano())
+	}
+	log.Info("监控已恢复")
+}
+
+// resumedAt returns when the monitor was last resumed after a pause
+func (m *ForumMonitor) resumedAt() time.Time {
+	if ns := m.resumed.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// IsPaused returns whether scheduled checks are paused
+func (m *ForumMonitor) IsPaused() bool {
+	return m.paused.Load()
+}
+
+// CheckNow triggers an immediate check of one source, or of every source when
+// url is empty. Manual checks run even while the monitor is paused.
+func (m *ForumMonitor) CheckNow(url string) error {
+	if err := m.sched.trigger(url); err != nil {
+		return err
+	}
+
+	// Dispatch right away instead of waiting for the next tick
+	m.dispatchDue()
+	return nil
+}
+
+// MonitorStatus describes the lifecycle state of the monitor
+type MonitorStatus struct {
+	Running       bool                   `json:"running"`
+	Paused        bool                   `json:"paused"`
+	CheckRunning  bool                   `json:"check_running"`
+	ActiveSources []string               `json:"active_sources"`
+	AICache       filter.AICacheStats    `json:"ai_cache"`
+	AIProviders   []filter.ProviderState `json:"ai_providers,omitempty"` // circuit breakers in fallback order
+}
+
+// Status returns the lifecycle state of the monitor
+func (m *ForumMonitor) Status() MonitorStatus {
+	active := m.sched.active()
+	if active == nil {
+		active = []string{}
+	}
+
+	status := MonitorStatus{
+		Running:       m.IsRunning(),
+		Paused:        m.IsPaused(),
+		CheckRunning:  len(active) > 0,
+		ActiveSources: active,
+		AICache:       filter.GetAICacheStats(),
+	}
+	if chain, ok := m.currentFilters().ai.(*filter.ChainAIFilter); ok {
+		status.AIProviders = chain.ProviderStates()
+	}
+	return status
+}
+
+// HealthStatus describes whether the monitor is making progress
+type HealthStatus struct {
+	StartedAt time.Time      `json:"started_at"`
+	LastCycle *CycleStatus   `json:"last_cycle"`
+	Stalled   bool           `json:"stalled"`
+	Sources   []SourceStatus `json:"sources"`
+}
+
+// minStallThreshold is the shortest time without a completed cycle before the monitor counts as stalled
+const minStallThreshold = 5 * time.Minute
+
+// Health reports the last completed cycle, per-source outcomes and whether the
+// monitor is stalled: running and not paused, yet no cycle completed within
+// three times the longest source interval.
+func (m *ForumMonitor) Health() HealthStatus {
+	health := HealthStatus{
+		StartedAt: m.startedAt,
+		LastCycle: m.sched.lastCompletedCycle(),
+		Sources:   m.sched.status(),
+	}
+
+	if !m.IsRunning() || m.IsPaused() || m.startedAt.IsZero() || len(health.Sources) == 0 {
+		return health
+	}
+
+	threshold := 3 * m.sched.maxInterval()
+	if threshold < minStallThreshold {
+		threshold = minStallThreshold
+	}
+
+	lastProgress := m.startedAt
+	if health.LastCycle != nil && health.LastCycle.FinishedAt.After(lastProgress) {
+		lastProgress = health.LastCycle.FinishedAt
+	}
+	if m.resumedAt().After(lastProgress) {
+		lastProgress = m.resumedAt()
+	}
+	health.Stalled = time.Since(lastProgress) > threshold
+
+	return health
+}
+
+// monitorLoop dispatches due sources to the worker pool
+func (m *ForumMonitor) monitorLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		m.dispatchDue()
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue hands as many due sources as there are idle workers to the pool
+func (m *ForumMonitor) dispatchDue() {
+	m.dispatchMu.Lock()
+	defer m.dispatchMu.Unlock()
+
+	// Sources are only taken when a worker is free to run them, so that a
+	// source never waits for a worker while marked as running
+	idle := len(m.workerStops) - int(m.inFlight.Load())
+	if idle <= 0 {
+		return
+	}
+
+	for _, src := range m.sched.takeDue(time.Now(), idle, m.IsPaused()) {
+		m.inFlight.Add(1)
+		select {
+		case m.jobs <- src:
+		case <-m.ctx.Done():
+			m.inFlight.Add(-1)
+			return
+		}
+	}
+}
+
+// resizeWorkers starts or retires workers until the pool has n of them, at
+// least one. A retired worker finishes its current check before it exits;
+// until then the idle count of dispatchDue already leaves it out.
+func (m *ForumMonitor) resizeWorkers(n int) {
+	m.dispatchMu.Lock()
+	defer m.dispatchMu.Unlock()
+
+	n = max(n, 1)
+
+	if n == len(m.workerStops) {
+		return
+	}
+	if len(m.workerStops) > 0 {
+		log.Infof("Worker 数量调整为 %d", n)
+	}
+
+	for len(m.workerStops) < n {
+		stop := make(chan struct{})
+		m.workerStops = append(m.workerStops, stop)
+		m.wg.Add(1)
+		go m.worker(stop)
+	}
+	for len(m.workerStops) > n {
+		last := len(m.workerStops) - 1
+		close(m.workerStops[last])
+		m.workerStops = m.workerStops[:last]
+	}
+}
+
+// worker runs queued source checks until it is retired or the monitor stops
+func (m *ForumMonitor) worker(stop <-chan struct{}) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-stop:
+			return
+		case src := <-m.jobs:
+			started := time.Now()
+			err := m.runSource(src)
+			m.sched.finish(src.URL, started, err)
+			m.inFlight.Add(-1)
+		}
+	}
+}
+
+// runSource performs one check of a single source
+func (m *ForumMonitor) runSource(src source) error {
+	started := time.Now()
+
+	var err error
+	switch src.Kind {
+	case sourceRSS:
+		err = m.processRSSFeed(src.URL)
+	case sourceExtra:
+		err = m.checkExtraURL(src.URL)
+	default:
+		err = fmt.Errorf("未知的来源类型: %s", src.Kind)
+	}
+
+	if err != nil {
+		log.Warnf("检查 %s 失败: %v", src.URL, err)
+		return err
+	}
+
+	log.Infof("[%s] 检查 %s 完成，耗时 %v，%v 后再次检查",
+		time.Now().Format("2006-01-02 15:04:05"), src.URL,
+		time.Since(started).Round(time.Millisecond), src.Interval)
+	return nil
+}
+
+// checkExtraURL checks a single extra thread URL directly
+func (m *ForumMonitor) checkExtraURL(url string) error {
+	unlock := m.lockThread(url)
+	defer unlock()
+
+	// Check if thread already exists
+	thread, err := m.db.FindThread(url)
+	if err != nil {
+		return fmt.Errorf("查询线程失败: %w", err)
+	}
+
+	if thread != nil {
+		// Thread exists, fetch comments
+		m.fetchComments(thread)
+		return nil
+	}
+
+	// Thread doesn't exist, fetch and process
+	if err := m.fetchThreadPage(url); err != nil {
+		return fmt.Errorf("抓取线程页面失败: %w", err)
+	}
+	return nil
+}
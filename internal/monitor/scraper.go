@@ -19,26 +19,47 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	"github.com/imhuimie/let-monitor-go/internal/notifier"
 	log "github.com/sirupsen/logrus"
 )
 
 // Scraper handles web scraping operations
 type Scraper struct {
-	client *http.Client
+	client  *http.Client
+	limiter *HostLimiter
 }
 
 // NewScraper creates a new scraper
-func NewScraper() *Scraper {
+func NewScraper(limiter *HostLimiter) *Scraper {
 	return &Scraper{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		limiter: limiter,
 	}
 }
 
+// get performs a rate-limited GET request
+func (s *Scraper) get(pageURL string) (*http.Response, error) {
+	if err := s.limiter.Wait(pageURL); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Get(pageURL)
+	if err != nil {
+		metrics.ObserveFetch("page", pageURL, 0, err, start)
+		return nil, err
+	}
+	metrics.ObserveFetch("page", pageURL, resp.StatusCode, nil, start)
+	return resp, nil
+}
+
 // FetchThreadPage fetches and parses a thread page
 func (s *Scraper) FetchThreadPage(threadURL string) (*database.Thread, error) {
-	resp, err := s.client.Get(threadURL)
+	resp, err := s.get(threadURL)
 	if err != nil {
 		return nil, fmt.Errorf("获取页面失败: %w", err)
 	}
@@ -107,7 +128,7 @@ func (s *Scraper) parseThreadPage(doc *goquery.Document, threadURL string) (*dat
 func (s *Scraper) FetchCommentsFromPage(threadURL string, page int) ([]*database.Comment, error) {
 	pageURL := fmt.Sprintf("%s/p%d", threadURL, page)
 
-	resp, err := s.client.Get(pageURL)
+	resp, err := s.get(pageURL)
 	if err != nil {
 		return nil, fmt.Errorf("获取页面失败: %w", err)
 	}
@@ -172,16 +193,13 @@ func (s *Scraper) parseComments(doc *goquery.Document, threadURL string) ([]*dat
 			CommentID:         fmt.Sprintf("%s_%s", domain, cid),
 			ThreadURL:         threadURL,
 			Author:            author,
+			Role:              role,
 			Message:           message,
 			CreatedAt:         createdAt,
 			CreatedAtRecorded: time.Now().UTC(),
 			URL:               fmt.Sprintf("%s/comment/%s/#Comment_%s", threadURL, cid, cid),
 		}
 
-		// Store role for filtering (we'll need this in the caller)
-		// For now, we'll add all comments and filter later
-		_ = role // Will be used in filter logic
-
 		comments = append(comments, comment)
 	})
 
@@ -214,44 +232,61 @@ func (m *ForumMonitor) handleThread(thread *database.Thread) {
 		return // Already exists
 	}
 
+	// Only notify if published within 24 hours
+	tooOld := time.Since(thread.PubDate) > 24*time.Hour
+	thread.Status = database.StatusPending
+	if tooOld {
+		thread.Status = database.StatusTooOld
+	}
+	thread.Offer = filter.ExtractOffer(thread.Title + "\n" + thread.Description)
+
 	// Insert thread into database
 	if err := m.db.InsertThread(thread); err != nil {
 		log.Warnf("插入线程失败: %v", err)
 		return
 	}
+	metrics.ObserveDiscovered("thread", thread.Domain)
 
-	// Only notify if published within 24 hours
-	age := time.Since(thread.PubDate)
-	if age > 24*time.Hour {
+	if tooOld {
 		log.Debugf("线程过旧，跳过通知: %s", thread.Title)
 		return
 	}
 
 	// Apply filters and send notification
+	thread.Decision = &database.Decision{}
 	m.notifyThread(thread)
 }
 
 // notifyThread applies filters and sends notification for a thread
 func (m *ForumMonitor) notifyThread(thread *database.Thread) {
-	cfg := m.config.Get()
-	aiDescription := ""
-
-	// Apply AI filter if enabled
-	if cfg.UseAIFilter && m.aiFilter != nil {
-		result, err := m.aiFilter.Filter(thread.Description, cfg.ThreadPrompt)
-		if err != nil {
-			log.Warnf("AI 过滤失败: %v", err)
-		} else if !m.aiFilter.IsValidResult(result) {
-			log.Debugf("AI 过滤拒绝线程: %s", thread.Title)
-			return
-		} else {
-			aiDescription = result
-		}
+	filters := m.currentFilters()
+
+	// Apply keyword and AI filters
+	doc := filter.ThreadDocument(thread)
+	prompt := renderPrompt(filters.threadPrompt, filter.ThreadPromptData(thread))
+	if status := filters.apply("thread", filters.threadKeywords, doc, thread.Description, prompt, thread.Decision); status != "" {
+		log.Debugf("线程未通过过滤 (%s): %s", status, thread.Title)
+		m.setThreadStatus(thread, status, filter.Summary(thread.Decision.AIResult))
+		return
 	}
+	aiDescription := filter.Summary(thread.Decision.AIResult)
 
-	// Send notification
-	if err := m.notifier.SendThread(thread, aiDescription); err != nil {
-		log.Warnf("发送通知失败: %v", err)
+	// Send notification, failed channels are queued for retry
+	deliveries := m.currentNotifier().DeliverThread(thread, aiDescription)
+	m.enqueueFailedDeliveries("thread", thread.Link, "", aiDescription, deliveries)
+	thread.Decision.Deliveries = deliveryRecords(deliveries)
+	m.setThreadStatus(thread, deliveryStatus(deliveries), aiDescription)
+}
+
+// setThreadStatus records the processing outcome and decision of a thread
+func (m *ForumMonitor) setThreadStatus(thread *database.Thread, status, aiSummary string) {
+	thread.Status = status
+	thread.AISummary = aiSummary
+	if thread.Decision != nil {
+		thread.Decision.DecidedAt = time.Now().UTC()
+	}
+	if err := m.db.UpdateThreadStatus(thread.Link, status, aiSummary, thread.Decision); err != nil {
+		log.Warnf("更新线程状态失败: %v", err)
 	}
 }
 
@@ -285,7 +320,6 @@ func (m *ForumMonitor) fetchComments(thread *database.Thread) {
 		}
 
 		m.processComments(thread, comments)
-		time.Sleep(1 * time.Second) // Rate limiting
 	}
 }
 
@@ -303,27 +337,34 @@ func (m *ForumMonitor) processComments(thread *database.Thread, comments []*data
 		if !m.shouldProcessComment(thread, comment) {
 			continue
 		}
+		decision := &database.Decision{}
+		if cfg.CommentFilter == "by_author" || cfg.CommentFilter == "by_role" {
+			decision.Filters = append(decision.Filters, "comment_filter:"+cfg.CommentFilter)
+		}
+
+		// Only notify if created within 24 hours and the thread is not muted
+		tooOld := time.Since(comment.CreatedAt) > 24*time.Hour
+		muted := cfg.IsThreadMuted(thread.Link)
+		comment.Status = database.StatusPending
+		if tooOld {
+			comment.Status = database.StatusTooOld
+		} else if muted {
+			comment.Status = database.StatusMuted
+		}
 
 		// Insert comment
 		if err := m.db.InsertComment(comment); err != nil {
 			log.Warnf("插入评论失败: %v", err)
 			continue
 		}
+		metrics.ObserveDiscovered("comment", thread.Domain)
 
-		// Only notify if created within 24 hours
-		age := time.Since(comment.CreatedAt)
-		if age > 24*time.Hour {
+		if tooOld || muted {
 			continue
 		}
+		comment.Decision = decision
 
-		// Apply keyword filter if enabled
-		if cfg.UseKeywordsFilter && m.keywordFilter != nil {
-			if !m.keywordFilter.Match(comment.Message) {
-				continue
-			}
-		}
-
-		// Apply AI filter and send notification
+		// Apply keyword and AI filters and send notification
 		m.notifyComment(thread, comment)
 	}
 }
@@ -337,9 +378,8 @@ func (m *ForumMonitor) shouldProcessComment(thread *database.Thread, comment *da
 		// Only process comments by thread creator
 		return comment.Author == thread.Creator
 	case "by_role":
-		// This would require storing role info, for now accept all non-member comments
-		// In a full implementation, we'd need to parse and check the role
-		return true
+		// Only process comments whose author holds one of the configured roles
+		return cfg.HasCommentRole(comment.Role)
 	default:
 		return true
 	}
@@ -347,24 +387,61 @@ func (m *ForumMonitor) shouldProcessComment(thread *database.Thread, comment *da
 
 // notifyComment applies filters and sends notification for a comment
 func (m *ForumMonitor) notifyComment(thread *database.Thread, comment *database.Comment) {
-	cfg := m.config.Get()
-	aiDescription := ""
+	filters := m.currentFilters()
+
+	// Apply keyword and AI filters
+	doc := filter.CommentDocument(thread, comment)
+	prompt := renderPrompt(filters.commentPrompt, filter.CommentPromptData(thread, comment))
+	if status := filters.apply("comment", filters.commentKeywords, doc, comment.Message, prompt, comment.Decision); status != "" {
+		log.Debugf("评论未通过过滤 (%s): %s", status, comment.URL)
+		m.setCommentStatus(comment, status, filter.Summary(comment.Decision.AIResult))
+		return
+	}
+	aiDescription := filter.Summary(comment.Decision.AIResult)
 
-	// Apply AI filter if enabled
-	if cfg.UseAIFilter && m.aiFilter != nil {
-		result, err := m.aiFilter.Filter(comment.Message, cfg.CommentPrompt)
-		if err != nil {
-			log.Warnf("AI 过滤失败: %v", err)
-		} else if !m.aiFilter.IsValidResult(result) {
-			log.Debugf("AI 过滤拒绝评论")
-			return
-		} else {
-			aiDescription = result
+	// Send notification, failed channels are queued for retry
+	deliveries := m.currentNotifier().DeliverComment(thread, comment, aiDescription)
+	m.enqueueFailedDeliveries("comment", thread.Link, comment.CommentID, aiDescription, deliveries)
+	comment.Decision.Deliveries = deliveryRecords(deliveries)
+	m.setCommentStatus(comment, deliveryStatus(deliveries), aiDescription)
+}
+
+// setCommentStatus records the processing outcome and decision of a comment
+func (m *ForumMonitor) setCommentStatus(comment *database.Comment, status, aiSummary string) {
+	comment.Status = status
+	comment.AISummary = aiSummary
+	if comment.Decision != nil {
+		comment.Decision.DecidedAt = time.Now().UTC()
+	}
+	if err := m.db.UpdateCommentStatus(comment.CommentID, status, aiSummary, comment.Decision); err != nil {
+		log.Warnf("更新评论状态失败: %v", err)
+	}
+}
+
+// deliveryRecords converts fan-out results into decision delivery records
+func deliveryRecords(deliveries []notifier.Delivery) []database.DeliveryRecord {
+	now := time.Now().UTC()
+	records := make([]database.DeliveryRecord, 0, len(deliveries))
+	for _, d := range deliveries {
+		record := database.DeliveryRecord{Channel: d.Channel, Status: database.DeliverySent, At: now}
+		if d.Err != nil {
+			record.Status = database.DeliveryQueued
+			record.Error = d.Err.Error()
 		}
+		records = append(records, record)
 	}
+	return records
+}
 
-	// Send notification
-	if err := m.notifier.SendComment(thread, comment, aiDescription); err != nil {
-		log.Warnf("发送通知失败: %v", err)
+// deliveryStatus summarizes the outcome of a notification fan-out
+func deliveryStatus(deliveries []notifier.Delivery) string {
+	if len(deliveries) == 0 {
+		return database.StatusUnrouted
+	}
+	for _, d := range deliveries {
+		if d.Err == nil {
+			return database.StatusNotified
+		}
 	}
+	return database.StatusQueued
 }
@@ -0,0 +1,122 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Chat Platform Webhooks"
+//   Timestamp: "2025-12-18T16:40:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Discord, Slack and Teams incoming webhooks all take a JSON POST and differ only in the payload"
+//   Principle_Applied: "Aether-Engineering-DRY"
+//   Quality_Check: "Any 2xx accepted; one short Retry-After honored on 429"
+// }}
+
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	chatWebhookTimeout = 10 * time.Second
+	// chatWebhookMaxRetryAfter is the longest Retry-After waited for before
+	// the single retry; longer waits are left to the outbox
+	chatWebhookMaxRetryAfter = 30 * time.Second
+)
+
+// postChatWebhook posts a JSON payload to the incoming webhook of a chat
+// platform. name is the platform, used in logs and errors. The request and
+// the Retry-After wait end with ErrClosed when stop is closed.
+func postChatWebhook(stop *closer, client *http.Client, name, webhookURL string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化 %s 消息失败: %w", name, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := stop.postJSON(client, webhookURL, data)
+		if errors.Is(err, ErrClosed) {
+			return err
+		}
+		if err != nil {
+			log.Warnf("发送 %s 消息失败: %v", name, err)
+			return err
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			log.Infof("%s 消息发送成功", name)
+			return nil
+		}
+
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 1 && retryAfter > 0 && retryAfter <= chatWebhookMaxRetryAfter {
+			log.Warnf("%s 发送过于频繁，%v 后重试", name, retryAfter)
+			if err := stop.sleep(retryAfter); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Warnf("%s API 返回非 2xx 状态码: %d, 响应: %s", name, resp.StatusCode, string(body))
+		return fmt.Errorf("%s API 错误 (状态码 %d): %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+// parseRetryAfter parses a Retry-After header in seconds, which Discord
+// sends with a fraction; 0 when absent or invalid
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// clip cuts text to at most n characters, marking the cut with an ellipsis
+func clip(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n-1]) + "…"
+}
+
+// commentAuthor returns the comment author followed by the forum role, if any
+func commentAuthor(comment *database.Comment) string {
+	if comment.Role == "" {
+		return comment.Author
+	}
+	return comment.Author + "（" + comment.Role + "）"
+}
+
+// verdictFact is a structured field of a JSON AI verdict
+type verdictFact struct {
+	label string
+	value string
+	code  bool // shown as code, e.g. a coupon
+}
+
+// verdictFacts returns the cheapest plan and coupon of a JSON AI verdict
+func verdictFacts(decision *database.Decision) []verdictFact {
+	if decision == nil || decision.AIVerdict == nil {
+		return nil
+	}
+
+	var facts []verdictFact
+	if plan := decision.AIVerdict.CheapestPlan; plan != "" {
+		facts = append(facts, verdictFact{label: "最低价套餐", value: plan})
+	}
+	if coupon := decision.AIVerdict.Coupon; coupon != "" {
+		facts = append(facts, verdictFact{label: "优惠码", value: coupon, code: true})
+	}
+	return facts
+}
@@ -11,60 +11,165 @@
 package notifier
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/config"
 	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/notifier/webhook"
 	"github.com/imhuimie/let-monitor-go/internal/utils"
 	log "github.com/sirupsen/logrus"
 )
 
 // CustomNotifier sends notifications via custom webhook
 type CustomNotifier struct {
-	webhookURL string
+	*closer
+	webhookURL string // {message} is replaced by the URL-encoded notification text, see expandMessage
+	options    config.WebhookConfig
+	body       *webhook.Template // nil when no body is sent
 	client     *http.Client
 }
 
-// NewCustomNotifier creates a new custom notifier
-func NewCustomNotifier(webhookURL string) *CustomNotifier {
-	return &CustomNotifier{
+// NewCustomNotifier creates a new custom notifier. Empty options send the
+// legacy GET request to webhookURL.
+func NewCustomNotifier(webhookURL string, options config.WebhookConfig) (*CustomNotifier, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &CustomNotifier{
+		closer:     newCloser(),
 		webhookURL: webhookURL,
+		options:    options,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: time.Duration(options.Timeout) * time.Second,
 		},
 	}
+	if options.Body != "" {
+		body, err := webhook.Parse(options.Body, options.BodyType)
+		if err != nil {
+			return nil, fmt.Errorf("webhook.body 无效: %w", err)
+		}
+		c.body = body
+	}
+	return c, nil
 }
 
 // Send sends a message via custom webhook
 func (c *CustomNotifier) Send(message string) error {
-	url := strings.ReplaceAll(c.webhookURL, "{message}", message)
+	return c.send(webhook.Data{Kind: "message", Text: message})
+}
+
+// SendThread sends a thread notification
+func (c *CustomNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	return c.send(webhook.Data{
+		Kind:      "thread",
+		Text:      utils.FormatThreadMessage(thread, aiDescription),
+		AISummary: aiDescription,
+		Thread:    thread,
+	})
+}
+
+// SendComment sends a comment notification
+func (c *CustomNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	return c.send(webhook.Data{
+		Kind:      "comment",
+		Text:      utils.FormatCommentMessage(thread, comment, aiDescription),
+		AISummary: aiDescription,
+		Thread:    thread,
+		Comment:   comment,
+	})
+}
+
+// send renders the request and sends it, retrying network errors, 429 and
+// 5xx responses up to options.Retries times. Close cancels the request and
+// the wait between attempts with ErrClosed.
+func (c *CustomNotifier) send(data webhook.Data) error {
+	target := expandMessage(c.webhookURL, data.Text)
+
+	var body []byte
+	if c.body != nil {
+		var err error
+		if body, err = c.body.Render(data); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt <= c.options.Retries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("自定义通知发送失败，%d 秒后重试: %v", attempt, err)
+			if err := c.sleep(time.Duration(attempt) * time.Second); err != nil {
+				return err
+			}
+		}
+
+		var retry bool
+		if retry, err = c.do(target, body); err == nil {
+			log.Info("自定义通知发送成功")
+			return nil
+		}
+		if !retry {
+			break
+		}
+	}
+	return err
+}
 
-	resp, err := c.client.Get(url)
+// do sends one request and reports whether a failure is worth retrying
+func (c *CustomNotifier) do(target string, body []byte) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(c.ctx, c.options.Method, target, reader)
 	if err != nil {
-		log.Warnf("发送自定义通知失败: %v", err)
-		return err
+		return false, fmt.Errorf("创建自定义通知请求失败: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Warnf("自定义通知 API 返回非 200 状态码: %d", resp.StatusCode)
-		return fmt.Errorf("自定义通知 API 错误: 状态码 %d", resp.StatusCode)
+	// Configured headers first, so that they can never replace the body headers
+	for name, value := range c.options.Headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", webhook.ContentType(c.options.BodyType))
+		if c.options.Secret != "" {
+			req.Header.Set(c.options.SignatureHeader, webhook.Sign(c.options.Secret, body))
+		}
 	}
 
-	log.Info("自定义通知发送成功")
-	return nil
-}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if c.ctx.Err() != nil {
+			return false, ErrClosed
+		}
+		log.Warnf("发送自定义通知失败: %v", err)
+		return true, err
+	}
+	defer resp.Body.Close()
 
-// SendThread sends a thread notification
-func (c *CustomNotifier) SendThread(thread *database.Thread, aiDescription string) error {
-	message := utils.FormatThreadMessage(thread, aiDescription)
-	return c.Send(message)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Warnf("自定义通知 API 返回非 2xx 状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("自定义通知 API 错误: 状态码 %d", resp.StatusCode)
+	}
+	return false, nil
 }
 
-// SendComment sends a comment notification
-func (c *CustomNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
-	message := utils.FormatCommentMessage(thread, comment, aiDescription)
-	return c.Send(message)
+// expandMessage replaces {message} in a webhook URL with the escaped text.
+// In the path, as in Bark's https://host/key/{message}, it is path-escaped so
+// that spaces become %20; in the query it is query-escaped.
+func expandMessage(webhookURL, text string) string {
+	path, query, hasQuery := strings.Cut(webhookURL, "?")
+	path = strings.ReplaceAll(path, "{message}", url.PathEscape(text))
+	if !hasQuery {
+		return path
+	}
+	return path + "?" + strings.ReplaceAll(query, "{message}", url.QueryEscape(text))
 }
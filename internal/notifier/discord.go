@@ -0,0 +1,169 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Discord Notifier"
+//   Timestamp: "2025-12-18T16:50:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Plain text in Discord loses the title link, author and time the embed layout shows natively"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "Embed field lengths clipped to the Discord limits, including the 6000 character total"
+// }}
+
+package notifier
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+)
+
+// Discord embed colors of thread and comment notifications
+const (
+	discordThreadColor  = 0x3498DB
+	discordCommentColor = 0x2ECC71
+)
+
+// discordEmbedLimit is the most characters Discord accepts in the title,
+// description, fields, footer and author of an embed together
+const discordEmbedLimit = 6000
+
+// DiscordNotifier sends notifications to a Discord webhook as embeds
+type DiscordNotifier struct {
+	*closer
+	webhookURL string
+	client     *http.Client
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title,omitempty"`       // up to 256 characters
+	URL         string              `json:"url,omitempty"`         // link of the title
+	Description string              `json:"description,omitempty"` // up to 4096 characters
+	Color       int                 `json:"color,omitempty"`
+	Author      *discordEmbedAuthor `json:"author,omitempty"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"` // ISO 8601
+}
+
+type discordEmbedAuthor struct {
+	Name string `json:"name"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"` // up to 1024 characters
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// NewDiscordNotifier creates a new Discord notifier
+func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{
+		closer:     newCloser(),
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: chatWebhookTimeout,
+		},
+	}
+}
+
+// Send sends a plain text message
+func (d *DiscordNotifier) Send(message string) error {
+	return d.post(discordMessage{Content: clip(message, 2000)})
+}
+
+// SendThread sends a thread notification
+func (d *DiscordNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	embed := discordEmbed{
+		Title:     clip(thread.Title, 256),
+		URL:       thread.Link,
+		Color:     discordThreadColor,
+		Author:    discordAuthor(thread.Creator),
+		Fields:    discordVerdictFields(thread.Decision),
+		Footer:    &discordEmbedFooter{Text: strings.ToUpper(thread.Domain) + " 新促销"},
+		Timestamp: thread.PubDate.UTC().Format(time.RFC3339),
+	}
+	embed.setDescription(aiDescription)
+	return d.post(discordMessage{Embeds: []discordEmbed{embed}})
+}
+
+// SendComment sends a comment notification; the title links to the comment
+func (d *DiscordNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	description := comment.Message
+	if aiDescription != "" {
+		description += "\n\n" + aiDescription
+	}
+
+	embed := discordEmbed{
+		Title:  clip(thread.Title, 256),
+		URL:    comment.URL,
+		Color:  discordCommentColor,
+		Author: discordAuthor(commentAuthor(comment)),
+		Fields: append([]discordEmbedField{
+			{Name: "线程", Value: clip(thread.Link, 1024)},
+		}, discordVerdictFields(comment.Decision)...),
+		Footer:    &discordEmbedFooter{Text: strings.ToUpper(thread.Domain) + " 新评论"},
+		Timestamp: comment.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	embed.setDescription(description)
+	return d.post(discordMessage{Embeds: []discordEmbed{embed}})
+}
+
+// post sends a message to the webhook
+func (d *DiscordNotifier) post(msg discordMessage) error {
+	return postChatWebhook(d.closer, d.client, "Discord", d.webhookURL, msg)
+}
+
+// setDescription sets the description clipped to 4096 characters and to what
+// the other parts of the embed leave of discordEmbedLimit
+func (e *discordEmbed) setDescription(text string) {
+	used := utf8.RuneCountInString(e.Title)
+	if e.Author != nil {
+		used += utf8.RuneCountInString(e.Author.Name)
+	}
+	if e.Footer != nil {
+		used += utf8.RuneCountInString(e.Footer.Text)
+	}
+	for _, field := range e.Fields {
+		used += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+
+	limit := min(4096, discordEmbedLimit-used)
+	if limit > 0 {
+		e.Description = clip(text, limit)
+	}
+}
+
+// discordAuthor returns the embed author, nil when unknown
+func discordAuthor(name string) *discordEmbedAuthor {
+	if name == "" {
+		return nil
+	}
+	return &discordEmbedAuthor{Name: clip(name, 256)}
+}
+
+// discordVerdictFields returns the AI verdict as inline fields, the coupon as code
+func discordVerdictFields(decision *database.Decision) []discordEmbedField {
+	var fields []discordEmbedField
+	for _, fact := range verdictFacts(decision) {
+		var value string
+		if fact.code {
+			// Clip before wrapping so that the closing backtick is kept
+			value = "`" + clip(strings.ReplaceAll(fact.value, "`", ""), 1022) + "`"
+		} else {
+			value = clip(fact.value, 1024)
+		}
+		fields = append(fields, discordEmbedField{Name: fact.label, Value: value, Inline: true})
+	}
+	return fields
+}
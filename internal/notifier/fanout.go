@@ -0,0 +1,252 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Fan-out Notifier with Channel Routing"
+//   Timestamp: "2025-12-02T10:15:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Extended single-backend notifier factory to multiple channels"
+//   Principle_Applied: "Aether-Engineering-SOLID-O, Composite Pattern"
+//   Quality_Check: "Per-channel routing rules, errors aggregated across channels"
+// }}
+
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// Channel is a named notifier with routing rules
+type Channel struct {
+	Name     string
+	Type     string
+	Notifier Notifier
+
+	rules    config.ChannelRules
+	keywords *filter.KeywordFilter
+}
+
+// NewChannel creates a channel from its configuration
+func NewChannel(ch config.ChannelConfig) (*Channel, error) {
+	ntf, err := NewChannelNotifier(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ch.Name
+	if name == "" {
+		name = ch.Type
+	}
+
+	c := &Channel{
+		Name:     name,
+		Type:     ch.Type,
+		Notifier: ntf,
+		rules:    ch.Rules,
+	}
+	if ch.Rules.Keywords != "" {
+		keywords, err := filter.NewKeywordFilter(ch.Rules.Keywords)
+		if err != nil {
+			return nil, fmt.Errorf("渠道 %s 的关键词规则无效: %w", name, err)
+		}
+		c.keywords = keywords
+	}
+
+	return c, nil
+}
+
+// AcceptsThread checks whether a thread notification should be routed to this channel
+func (c *Channel) AcceptsThread(thread *database.Thread) bool {
+	if !c.acceptsKind("thread") || !c.acceptsSource(thread) {
+		return false
+	}
+	if c.keywords != nil {
+		if _, ok := c.keywords.MatchDocument(filter.ThreadDocument(thread)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// AcceptsComment checks whether a comment notification should be routed to this channel
+func (c *Channel) AcceptsComment(thread *database.Thread, comment *database.Comment) bool {
+	if !c.acceptsKind("comment") || !c.acceptsSource(thread) {
+		return false
+	}
+	if c.keywords != nil {
+		if _, ok := c.keywords.MatchDocument(filter.CommentDocument(thread, comment)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsKind checks the notification kind against the channel rules
+func (c *Channel) acceptsKind(kind string) bool {
+	if len(c.rules.Kinds) == 0 {
+		return true
+	}
+	for _, k := range c.rules.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsSource checks the thread domain and category against the channel rules
+func (c *Channel) acceptsSource(thread *database.Thread) bool {
+	if len(c.rules.Domains) > 0 {
+		matched := false
+		for _, d := range c.rules.Domains {
+			if d != "" && strings.Contains(strings.ToLower(thread.Domain), strings.ToLower(d)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(c.rules.Categories) > 0 {
+		matched := false
+		for _, cat := range c.rules.Categories {
+			if strings.EqualFold(cat, thread.Category) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FanoutNotifier sends notifications to every matching channel
+type FanoutNotifier struct {
+	channels []*Channel
+}
+
+// Ensure FanoutNotifier implements Notifier interface
+var _ Notifier = (*FanoutNotifier)(nil)
+
+// NewFanoutNotifier creates a fan-out notifier over all enabled channels
+func NewFanoutNotifier(cfg *config.Config) (*FanoutNotifier, error) {
+	f := &FanoutNotifier{}
+
+	for _, chCfg := range cfg.NotificationChannels() {
+		ch, err := NewChannel(chCfg)
+		if err != nil {
+			return nil, fmt.Errorf("创建通知渠道 %s 失败: %w", chCfg.Name, err)
+		}
+		f.channels = append(f.channels, ch)
+	}
+
+	return f, nil
+}
+
+// Channels returns the configured channels
+func (f *FanoutNotifier) Channels() []*Channel {
+	return f.channels
+}
+
+// Close ends the waits of sends in progress on every channel; those sends
+// fail with ErrClosed and are left to the outbox
+func (f *FanoutNotifier) Close() error {
+	for _, ch := range f.channels {
+		if c, ok := ch.Notifier.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	return nil
+}
+
+// Send sends a plain message to every channel
+func (f *FanoutNotifier) Send(message string) error {
+	var errs []error
+	for _, ch := range f.channels {
+		if err := ch.Notifier.Send(message); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Delivery is the outcome of sending a notification to one channel
+type Delivery struct {
+	Channel string
+	Err     error
+}
+
+// Channel returns the channel with the given name, or nil
+func (f *FanoutNotifier) Channel(name string) *Channel {
+	for _, ch := range f.channels {
+		if ch.Name == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+// DeliverThread sends a thread notification to the channels whose rules accept it
+// and reports the result for each of them
+func (f *FanoutNotifier) DeliverThread(thread *database.Thread, aiDescription string) []Delivery {
+	var deliveries []Delivery
+	for _, ch := range f.channels {
+		if !ch.AcceptsThread(thread) {
+			log.Debugf("通知渠道 %s 规则不匹配，跳过线程: %s", ch.Name, thread.Title)
+			continue
+		}
+		err := ch.Notifier.SendThread(thread, aiDescription)
+		metrics.ObserveNotification(ch.Name, err)
+		deliveries = append(deliveries, Delivery{Channel: ch.Name, Err: err})
+	}
+	return deliveries
+}
+
+// DeliverComment sends a comment notification to the channels whose rules accept it
+// and reports the result for each of them
+func (f *FanoutNotifier) DeliverComment(thread *database.Thread, comment *database.Comment, aiDescription string) []Delivery {
+	var deliveries []Delivery
+	for _, ch := range f.channels {
+		if !ch.AcceptsComment(thread, comment) {
+			log.Debugf("通知渠道 %s 规则不匹配，跳过评论: %s", ch.Name, comment.CommentID)
+			continue
+		}
+		err := ch.Notifier.SendComment(thread, comment, aiDescription)
+		metrics.ObserveNotification(ch.Name, err)
+		deliveries = append(deliveries, Delivery{Channel: ch.Name, Err: err})
+	}
+	return deliveries
+}
+
+// SendThread sends a thread notification to the channels whose rules accept it
+func (f *FanoutNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	return joinDeliveryErrors(f.DeliverThread(thread, aiDescription))
+}
+
+// SendComment sends a comment notification to the channels whose rules accept it
+func (f *FanoutNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	return joinDeliveryErrors(f.DeliverComment(thread, comment, aiDescription))
+}
+
+// joinDeliveryErrors combines the failed deliveries into a single error
+func joinDeliveryErrors(deliveries []Delivery) error {
+	var errs []error
+	for _, d := range deliveries {
+		if d.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Channel, d.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
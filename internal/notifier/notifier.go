@@ -24,16 +24,63 @@ type Notifier interface {
 	SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error
 }
 
-// NewNotifier creates a notifier based on configuration
+// TargetedNotifier is a notifier with several targets that can resend a
+// notification to some of them
+type TargetedNotifier interface {
+	Notifier
+	WithTargets(targets []string) (Notifier, error)
+}
+
+// TargetError reports a notification that reached only some targets of a
+// channel. Failed lists the targets it still has to be sent to.
+type TargetError struct {
+	Failed []string
+	Err    error
+}
+
+func (e *TargetError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// NewNotifier creates a notifier based on configuration. The result fans out
+// to every configured notification channel.
 func NewNotifier(cfg *config.Config) (Notifier, error) {
-	switch cfg.NoticeType {
+	return NewFanoutNotifier(cfg)
+}
+
+// NewChannelNotifier creates the notifier backing a single channel
+func NewChannelNotifier(ch config.ChannelConfig) (Notifier, error) {
+	switch ch.Type {
 	case "telegram":
-		return NewTelegramNotifier(cfg.TelegramBot, cfg.ChatID), nil
+		targets, err := ch.TelegramTargets()
+		if err != nil {
+			return nil, err
+		}
+		return NewTelegramNotifier(ch.TelegramBot, targets, TelegramOptions{
+			ParseMode:      ch.ParseMode,
+			DisablePreview: ch.DisablePreview,
+			MuteButton:     ch.MuteButton,
+			RateLimit:      ch.RateLimit,
+		}), nil
 	case "wechat":
-		return NewWeChatNotifier(cfg.WeChatKey), nil
+		return NewWeChatNotifier(ch.WeChatKey), nil
 	case "custom":
-		return NewCustomNotifier(cfg.CustomURL), nil
+		custom, err := NewCustomNotifier(ch.CustomURL, ch.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		return custom, nil
+	case "discord":
+		return NewDiscordNotifier(ch.DiscordWebhook), nil
+	case "slack":
+		return NewSlackNotifier(ch.SlackWebhook), nil
+	case "teams":
+		return NewTeamsNotifier(ch.TeamsWebhook), nil
 	default:
-		return nil, fmt.Errorf("不支持的通知类型: %s", cfg.NoticeType)
+		return nil, fmt.Errorf("不支持的通知类型: %s", ch.Type)
 	}
 }
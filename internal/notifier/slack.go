@@ -0,0 +1,202 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Slack Notifier"
+//   Timestamp: "2025-12-18T17:10:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Slack incoming webhooks render Block Kit layouts; plain text is kept as the notification fallback"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "mrkdwn control characters escaped; block text clipped to the Slack limits"
+// }}
+
+package notifier
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/utils"
+)
+
+// SlackNotifier sends notifications to a Slack incoming webhook using Block Kit
+type SlackNotifier struct {
+	*closer
+	webhookURL string
+	client     *http.Client
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // fallback shown in notifications
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string        `json:"type"`
+	Text     *slackText    `json:"text,omitempty"`     // up to 3000 characters, 150 in headers
+	Fields   []slackText   `json:"fields,omitempty"`   // up to 2000 characters each
+	Elements []interface{} `json:"elements,omitempty"` // slackText in context, slackButton in actions
+}
+
+type slackText struct {
+	Type string `json:"type"` // "plain_text" or "mrkdwn"
+	Text string `json:"text"`
+}
+
+type slackButton struct {
+	Type string    `json:"type"` // "button"
+	Text slackText `json:"text"`
+	URL  string    `json:"url"`
+}
+
+// slackEscaper escapes the characters mrkdwn reserves for links and mentions
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackURLEscaper escapes a link target; "|" and ">" would end it early
+var slackURLEscaper = strings.NewReplacer("&", "&amp;", "<", "%3C", ">", "%3E", "|", "%7C")
+
+// NewSlackNotifier creates a new Slack notifier
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		closer:     newCloser(),
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: chatWebhookTimeout,
+		},
+	}
+}
+
+// Send sends a plain text message
+func (s *SlackNotifier) Send(message string) error {
+	return s.post(slackMessage{Text: slackEscaper.Replace(message)})
+}
+
+// SendThread sends a thread notification
+func (s *SlackNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	blocks := []slackBlock{
+		slackHeader(strings.ToUpper(thread.Domain) + " 新促销"),
+		slackSection("*" + slackLink(thread.Title, thread.Link) + "*"),
+		slackContext("作者："+thread.Creator, "时间："+thread.PubDate.Format("2006/01/02 15:04")),
+	}
+	if aiDescription != "" {
+		blocks = append(blocks, slackSection(slackEscape(aiDescription, 3000)))
+	}
+	blocks = appendSlackVerdict(blocks, thread.Decision)
+	blocks = append(blocks, slackActions(slackButtonTo("打开线程", thread.Link)))
+
+	return s.post(slackMessage{
+		Text:   slackEscaper.Replace(utils.FormatThreadMessage(thread, aiDescription)),
+		Blocks: blocks,
+	})
+}
+
+// SendComment sends a comment notification
+func (s *SlackNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	blocks := []slackBlock{
+		slackHeader(strings.ToUpper(thread.Domain) + " 新评论"),
+		slackSection("线程：*" + slackLink(thread.Title, thread.Link) + "*"),
+		slackContext("作者："+commentAuthor(comment), "时间："+comment.CreatedAt.Format("2006/01/02 15:04")),
+	}
+	if comment.Message != "" {
+		blocks = append(blocks, slackSection(slackEscape(comment.Message, 3000)))
+	}
+	if aiDescription != "" {
+		blocks = append(blocks, slackSection(slackEscape(aiDescription, 3000)))
+	}
+	blocks = appendSlackVerdict(blocks, comment.Decision)
+	blocks = append(blocks, slackActions(
+		slackButtonTo("打开线程", thread.Link),
+		slackButtonTo("打开评论", comment.URL),
+	))
+
+	return s.post(slackMessage{
+		Text:   slackEscaper.Replace(utils.FormatCommentMessage(thread, comment, aiDescription)),
+		Blocks: blocks,
+	})
+}
+
+// post sends a message to the webhook
+func (s *SlackNotifier) post(msg slackMessage) error {
+	return postChatWebhook(s.closer, s.client, "Slack", s.webhookURL, msg)
+}
+
+// slackHeader returns a header block of plain text
+func slackHeader(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: clip(text, 150)}}
+}
+
+// slackSection returns a section of mrkdwn text, which must already be
+// escaped and at most 3000 characters long
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}
+}
+
+// slackContext returns a context block showing the texts in small print
+func slackContext(texts ...string) slackBlock {
+	block := slackBlock{Type: "context"}
+	for _, text := range texts {
+		block.Elements = append(block.Elements, slackText{Type: "mrkdwn", Text: slackEscape(text, 3000)})
+	}
+	return block
+}
+
+// slackActions returns an actions block with the buttons that have a URL
+func slackActions(buttons ...slackButton) slackBlock {
+	block := slackBlock{Type: "actions"}
+	for _, button := range buttons {
+		if button.URL != "" {
+			block.Elements = append(block.Elements, button)
+		}
+	}
+	return block
+}
+
+// slackButtonTo returns a button opening url
+func slackButtonTo(text, url string) slackButton {
+	return slackButton{Type: "button", Text: slackText{Type: "plain_text", Text: text}, URL: url}
+}
+
+// slackLink formats an mrkdwn link with escaped text, clipped to 256 characters
+func slackLink(text, url string) string {
+	return "<" + slackURLEscaper.Replace(url) + "|" + slackEscape(text, 256) + ">"
+}
+
+// slackEscape escapes text for mrkdwn and clips the result to at most n
+// characters. The text is cut before escaping, so that an escape sequence is
+// never split.
+func slackEscape(text string, n int) string {
+	escaped := slackEscaper.Replace(text)
+	if utf8.RuneCountInString(escaped) <= n {
+		return escaped
+	}
+
+	var b strings.Builder
+	length := 0
+	for _, r := range text {
+		part := slackEscaper.Replace(string(r))
+		if length+utf8.RuneCountInString(part) > n-1 {
+			break
+		}
+		b.WriteString(part)
+		length += utf8.RuneCountInString(part)
+	}
+	return b.String() + "…"
+}
+
+// appendSlackVerdict adds the AI verdict as section fields, the coupon as code
+func appendSlackVerdict(blocks []slackBlock, decision *database.Decision) []slackBlock {
+	facts := verdictFacts(decision)
+	if len(facts) == 0 {
+		return blocks
+	}
+
+	block := slackBlock{Type: "section"}
+	for _, fact := range facts {
+		value := slackEscape(fact.value, 1900)
+		if fact.code {
+			value = "`" + strings.ReplaceAll(value, "`", "") + "`"
+		}
+		block.Fields = append(block.Fields, slackText{Type: "mrkdwn", Text: "*" + fact.label + "*\n" + value})
+	}
+	return append(blocks, block)
+}
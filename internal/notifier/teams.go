@@ -0,0 +1,202 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Microsoft Teams Notifier"
+//   Timestamp: "2025-12-18T17:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Teams Workflows webhooks replace Office 365 connectors; both accept an Adaptive Card attachment"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "Adaptive Card 1.4 with facts and open-URL actions; works with Workflows and legacy connectors"
+// }}
+
+package notifier
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+)
+
+// TeamsNotifier sends notifications to a Microsoft Teams webhook as Adaptive Cards
+type TeamsNotifier struct {
+	*closer
+	webhookURL string
+	client     *http.Client
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"` // "message"
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	ContentURL  *string      `json:"contentUrl"` // always null, required by legacy connectors
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"` // "AdaptiveCard"
+	Version string           `json:"version"`
+	Body    []interface{}    `json:"body"` // adaptiveTextBlock and adaptiveFactSet
+	Actions []adaptiveAction `json:"actions,omitempty"`
+	MSTeams *adaptiveMSTeams `json:"msteams,omitempty"`
+}
+
+type adaptiveTextBlock struct {
+	Type     string `json:"type"` // "TextBlock"
+	Text     string `json:"text"`
+	Size     string `json:"size,omitempty"`
+	Weight   string `json:"weight,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Wrap     bool   `json:"wrap"`
+}
+
+type adaptiveFactSet struct {
+	Type  string         `json:"type"` // "FactSet"
+	Facts []adaptiveFact `json:"facts"`
+}
+
+type adaptiveFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type adaptiveAction struct {
+	Type  string `json:"type"` // "Action.OpenUrl"
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type adaptiveMSTeams struct {
+	Width string `json:"width"` // "Full" uses the whole message width
+}
+
+// NewTeamsNotifier creates a new Teams notifier
+func NewTeamsNotifier(webhookURL string) *TeamsNotifier {
+	return &TeamsNotifier{
+		closer:     newCloser(),
+		webhookURL: webhookURL,
+		client: &http.Client{
+			Timeout: chatWebhookTimeout,
+		},
+	}
+}
+
+// Send sends a plain text message
+func (t *TeamsNotifier) Send(message string) error {
+	return t.post(teamsCard([]interface{}{teamsText(message)}, nil))
+}
+
+// SendThread sends a thread notification
+func (t *TeamsNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	body := []interface{}{
+		teamsSubtitle(strings.ToUpper(thread.Domain) + " 新促销"),
+		teamsTitle(thread.Title),
+		teamsFacts(
+			adaptiveFact{Title: "作者", Value: thread.Creator},
+			adaptiveFact{Title: "时间", Value: thread.PubDate.Format("2006/01/02 15:04")},
+		),
+	}
+	if aiDescription != "" {
+		body = append(body, teamsText(aiDescription))
+	}
+	if facts := teamsVerdictFacts(thread.Decision); len(facts) > 0 {
+		body = append(body, teamsFacts(facts...))
+	}
+
+	return t.post(teamsCard(body, teamsActions("打开线程", thread.Link)))
+}
+
+// SendComment sends a comment notification
+func (t *TeamsNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	body := []interface{}{
+		teamsSubtitle(strings.ToUpper(thread.Domain) + " 新评论"),
+		teamsTitle(thread.Title),
+		teamsFacts(
+			adaptiveFact{Title: "作者", Value: commentAuthor(comment)},
+			adaptiveFact{Title: "时间", Value: comment.CreatedAt.Format("2006/01/02 15:04")},
+		),
+	}
+	if comment.Message != "" {
+		body = append(body, teamsText(comment.Message))
+	}
+	if aiDescription != "" {
+		body = append(body, teamsText(aiDescription))
+	}
+	if facts := teamsVerdictFacts(comment.Decision); len(facts) > 0 {
+		body = append(body, teamsFacts(facts...))
+	}
+
+	return t.post(teamsCard(body, teamsActions("打开线程", thread.Link, "打开评论", comment.URL)))
+}
+
+// post sends a message to the webhook
+func (t *TeamsNotifier) post(msg teamsMessage) error {
+	return postChatWebhook(t.closer, t.client, "Teams", t.webhookURL, msg)
+}
+
+// teamsCard wraps an Adaptive Card into a Teams message
+func teamsCard(body []interface{}, actions []adaptiveAction) teamsMessage {
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				Actions: actions,
+				MSTeams: &adaptiveMSTeams{Width: "Full"},
+			},
+		}},
+	}
+}
+
+// teamsTitle returns a bold text block in a larger font
+func teamsTitle(text string) adaptiveTextBlock {
+	return adaptiveTextBlock{Type: "TextBlock", Text: text, Size: "Medium", Weight: "Bolder", Wrap: true}
+}
+
+// teamsSubtitle returns a dimmed text block
+func teamsSubtitle(text string) adaptiveTextBlock {
+	return adaptiveTextBlock{Type: "TextBlock", Text: text, IsSubtle: true, Wrap: true}
+}
+
+// teamsText returns a wrapping text block
+func teamsText(text string) adaptiveTextBlock {
+	return adaptiveTextBlock{Type: "TextBlock", Text: text, Wrap: true}
+}
+
+// teamsFacts returns a fact set of the facts with a value
+func teamsFacts(facts ...adaptiveFact) adaptiveFactSet {
+	set := adaptiveFactSet{Type: "FactSet"}
+	for _, fact := range facts {
+		if fact.Value != "" {
+			set.Facts = append(set.Facts, fact)
+		}
+	}
+	return set
+}
+
+// teamsVerdictFacts returns the AI verdict as facts
+func teamsVerdictFacts(decision *database.Decision) []adaptiveFact {
+	var facts []adaptiveFact
+	for _, fact := range verdictFacts(decision) {
+		facts = append(facts, adaptiveFact{Title: fact.label, Value: fact.value})
+	}
+	return facts
+}
+
+// teamsActions returns open-URL actions from title and URL pairs, skipping empty URLs
+func teamsActions(pairs ...string) []adaptiveAction {
+	var actions []adaptiveAction
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			actions = append(actions, adaptiveAction{Type: "Action.OpenUrl", Title: pairs[i], URL: pairs[i+1]})
+		}
+	}
+	return actions
+}
@@ -11,44 +11,311 @@
 package notifier
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/imhuimie/let-monitor-go/internal/config"
 	"github.com/imhuimie/let-monitor-go/internal/database"
-	"github.com/imhuimie/let-monitor-go/internal/utils"
 	log "github.com/sirupsen/logrus"
 )
 
-// TelegramNotifier sends notifications via Telegram
+// Inline keyboard buttons of telegram notifications. The interactive bot
+// reads the thread URL from the thread button when the mute button is pressed.
+const (
+	TelegramThreadButton  = "打开线程"
+	TelegramCommentButton = "打开评论"
+	TelegramMuteButton    = "静音此线程"
+	TelegramMuteCallback  = "mute_thread"
+)
+
+// TelegramOptions controls how telegram notifications are formatted
+type TelegramOptions struct {
+	ParseMode      string // config.TelegramParseHTML by default
+	DisablePreview bool   // no link preview under messages
+	MuteButton     bool   // add the mute button, see TelegramMuteCallback
+	RateLimit      int    // messages per minute to one chat, config.DefaultTelegramRateLimit by default
+}
+
+// TelegramNotifier sends notifications via Telegram to one or more chats
 type TelegramNotifier struct {
+	*closer
 	botToken string
-	chatID   string
+	targets  []config.TelegramTarget
+	options  TelegramOptions
+	interval time.Duration // minimum spacing of messages to one chat
+	markup   telegramMarkup
 	client   *http.Client
+	// parts of a split message already delivered to a target, set by
+	// WithTargets so that a retry continues with the first missing part
+	sentParts map[string]int
+}
+
+// telegramPartsSep separates a retried target from the number of parts it
+// already received, as in "-1001234567890:42#2"
+const telegramPartsSep = "#"
+
+// Ensure TelegramNotifier can retry single targets
+var _ TargetedNotifier = (*TelegramNotifier)(nil)
+
+// TelegramInlineKeyboard is the reply_markup of a message with inline buttons
+type TelegramInlineKeyboard struct {
+	InlineKeyboard [][]TelegramButton `json:"inline_keyboard"`
+}
+
+// TelegramButton is an inline keyboard button opening a URL or sending callback data
+type TelegramButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
 }
 
-// NewTelegramNotifier creates a new Telegram notifier
-func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
+// telegramSendMessage is the request body of sendMessage
+type telegramSendMessage struct {
+	ChatID             string                  `json:"chat_id"`
+	MessageThreadID    int                     `json:"message_thread_id,omitempty"`
+	Text               string                  `json:"text"`
+	ParseMode          string                  `json:"parse_mode,omitempty"`
+	LinkPreviewOptions *telegramLinkPreview    `json:"link_preview_options,omitempty"`
+	ReplyMarkup        *TelegramInlineKeyboard `json:"reply_markup,omitempty"`
+}
+
+type telegramLinkPreview struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+// NewTelegramNotifier creates a Telegram notifier sending every message to all targets
+func NewTelegramNotifier(botToken string, targets []config.TelegramTarget, options TelegramOptions) *TelegramNotifier {
+	if options.RateLimit <= 0 {
+		options.RateLimit = config.DefaultTelegramRateLimit
+	}
 	return &TelegramNotifier{
+		closer:   newCloser(),
 		botToken: botToken,
-		chatID:   chatID,
+		targets:  targets,
+		options:  options,
+		interval: time.Minute / time.Duration(options.RateLimit),
+		markup:   newTelegramMarkup(options.ParseMode),
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
-// Send sends a message via Telegram
+// Send sends a plain text message via Telegram, split when too long
 func (t *TelegramNotifier) Send(message string) error {
+	markup := plainMarkup{}
+	blocks := []telegramBlock{{text: message, raw: true}}
+	return t.sendParts(markup, splitTelegramMessage(markup, blocks, telegramMaxLength), nil)
+}
+
+// SendThread sends a thread notification
+func (t *TelegramNotifier) SendThread(thread *database.Thread, aiDescription string) error {
+	blocks := formatThreadBlocks(t.markup, thread, aiDescription)
+	if t.options.ParseMode == config.TelegramParsePlain {
+		blocks = plainThreadBlocks(thread, aiDescription)
+	}
+
+	keyboard := t.keyboard([]TelegramButton{{Text: TelegramThreadButton, URL: thread.Link}})
+	return t.sendParts(t.markup, splitTelegramMessage(t.markup, blocks, telegramMaxLength), keyboard)
+}
+
+// SendComment sends a comment notification
+func (t *TelegramNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
+	blocks := formatCommentBlocks(t.markup, thread, comment, aiDescription)
+	if t.options.ParseMode == config.TelegramParsePlain {
+		blocks = plainCommentBlocks(thread, comment, aiDescription)
+	}
+
+	keyboard := t.keyboard([]TelegramButton{
+		{Text: TelegramThreadButton, URL: thread.Link},
+		{Text: TelegramCommentButton, URL: comment.URL},
+	})
+	return t.sendParts(t.markup, splitTelegramMessage(t.markup, blocks, telegramMaxLength), keyboard)
+}
+
+// keyboard returns the inline keyboard with the link buttons and, when
+// enabled, the mute button on its own row
+func (t *TelegramNotifier) keyboard(links []TelegramButton) *TelegramInlineKeyboard {
+	var row []TelegramButton
+	for _, button := range links {
+		if button.URL != "" {
+			row = append(row, button)
+		}
+	}
+
+	keyboard := &TelegramInlineKeyboard{}
+	if len(row) > 0 {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+	}
+	if t.options.MuteButton && len(row) > 0 {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []TelegramButton{
+			{Text: TelegramMuteButton, CallbackData: TelegramMuteCallback},
+		})
+	}
+	if len(keyboard.InlineKeyboard) == 0 {
+		return nil
+	}
+	return keyboard
+}
+
+// WithTargets returns a notifier sending only to those of the given targets
+// that are still configured, used to retry the targets a message missed. A
+// target listed with the parts it already received only gets the rest.
+func (t *TelegramNotifier) WithTargets(targets []string) (Notifier, error) {
+	wanted := make(map[string]bool, len(targets))
+	sentParts := make(map[string]int)
+	for _, target := range targets {
+		if i := strings.LastIndex(target, telegramPartsSep); i >= 0 {
+			if n, err := strconv.Atoi(target[i+len(telegramPartsSep):]); err == nil {
+				target = target[:i]
+				sentParts[target] = n
+			}
+		}
+		wanted[target] = true
+	}
+
+	subset := *t
+	subset.targets = nil
+	subset.sentParts = sentParts
+	for _, target := range t.targets {
+		if wanted[target.String()] {
+			subset.targets = append(subset.targets, target)
+		}
+	}
+	if len(subset.targets) == 0 {
+		return nil, fmt.Errorf("Telegram 目标已不在配置中: %s", strings.Join(targets, ", "))
+	}
+	return &subset, nil
+}
+
+// sendParts sends a message to every target. A failed target does not stop
+// the others. When some targets succeeded, or a failed target received some
+// parts of a split message, the error is a *TargetError listing the failed
+// targets with the parts they received, so that a retry only sends the rest.
+func (t *TelegramNotifier) sendParts(markup telegramMarkup, parts []string, keyboard *TelegramInlineKeyboard) error {
+	if len(t.targets) == 0 {
+		return fmt.Errorf("未配置 Telegram chat_id")
+	}
+
+	var errs []error
+	var failed []string
+	partial := false
+	for _, target := range t.targets {
+		skip := t.sentParts[target.String()]
+		if skip >= len(parts) {
+			// The message was split differently this time; send all of it
+			skip = 0
+		}
+
+		sent, err := t.sendTarget(target, markup, parts, skip, keyboard)
+		if err == nil {
+			continue
+		}
+		if len(t.targets) > 1 {
+			err = fmt.Errorf("发送到 %s 失败: %w", target, err)
+		}
+		errs = append(errs, err)
+		if sent > 0 {
+			failed = append(failed, target.String()+telegramPartsSep+strconv.Itoa(sent))
+			partial = true
+		} else {
+			failed = append(failed, target.String())
+		}
+	}
+	switch {
+	case len(failed) == len(t.targets) && !partial:
+		return errors.Join(errs...)
+	case len(failed) > 0:
+		return &TargetError{Failed: failed, Err: errors.Join(errs...)}
+	}
+
+	log.Info("Telegram 消息发送成功")
+	return nil
+}
+
+// sendTarget sends the parts of a message after the first skip to one target
+// in order; the keyboard is attached to the last part. It returns the number
+// of parts the target has received.
+func (t *TelegramNotifier) sendTarget(target config.TelegramTarget, markup telegramMarkup, parts []string, skip int, keyboard *TelegramInlineKeyboard) (int, error) {
+	for i := skip; i < len(parts); i++ {
+		msg := telegramSendMessage{
+			ChatID:          target.ChatID,
+			MessageThreadID: target.MessageThreadID,
+			Text:            parts[i],
+			ParseMode:       markup.apiMode(),
+		}
+		if t.options.DisablePreview {
+			msg.LinkPreviewOptions = &telegramLinkPreview{IsDisabled: true}
+		}
+		if i == len(parts)-1 {
+			msg.ReplyMarkup = keyboard
+		}
+		if err := t.sendMessage(msg); err != nil {
+			if len(parts) > 1 {
+				return i, fmt.Errorf("发送第 %d/%d 段消息失败: %w", i+1, len(parts), err)
+			}
+			return i, err
+		}
+	}
+	return len(parts), nil
+}
+
+// sendMessage sends one message, paced per chat. It waits and retries when
+// Telegram answers with a short retry_after, and follows a group that was
+// upgraded to a supergroup. Waits and requests end with ErrClosed once the
+// notifier is closed.
+func (t *TelegramNotifier) sendMessage(msg telegramSendMessage) error {
+	for attempt := 1; ; attempt++ {
+		key := t.botToken + "|" + msg.ChatID
+		if wait := telegramPace.reserve(key, t.interval); wait > 0 {
+			if err := t.sleep(wait); err != nil {
+				return err
+			}
+		}
+
+		err := t.post(msg)
+		var apiErr *TelegramError
+		if !errors.As(err, &apiErr) {
+			return err
+		}
+		if apiErr.RetryAfter > 0 {
+			telegramPace.delay(key, apiErr.RetryAfter)
+		}
+		if attempt == telegramMaxAttempts {
+			return err
+		}
+
+		switch {
+		case apiErr.MigrateToChatID != 0:
+			log.Warnf("Telegram 群组 %s 已升级为超级群组，请将 chat_id 改为 %d", msg.ChatID, apiErr.MigrateToChatID)
+			msg.ChatID = strconv.FormatInt(apiErr.MigrateToChatID, 10)
+		case apiErr.RetryAfter > 0 && apiErr.RetryAfter <= telegramMaxRetryAfter:
+			log.Warnf("Telegram 发送过于频繁，%v 后重试 (chat %s)", apiErr.RetryAfter, msg.ChatID)
+		default:
+			return err
+		}
+	}
+}
+
+// post calls sendMessage with a JSON body
+func (t *TelegramNotifier) post(msg telegramSendMessage) error {
 	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.botToken)
 
-	params := url.Values{}
-	params.Set("chat_id", t.chatID)
-	params.Set("text", message)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化 Telegram 消息失败: %w", err)
+	}
 
-	resp, err := t.client.PostForm(apiURL, params)
+	resp, err := t.postJSON(t.client, apiURL, data)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
 	if err != nil {
 		log.Warnf("发送 Telegram 消息失败: %v", err)
 		return err
@@ -58,21 +325,7 @@ func (t *TelegramNotifier) Send(message string) error {
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Warnf("Telegram API 返回非 200 状态码: %d, 响应: %s", resp.StatusCode, string(body))
-		return fmt.Errorf("Telegram API 错误 (状态码 %d): %s", resp.StatusCode, string(body))
+		return ParseTelegramError(resp.StatusCode, body)
 	}
-
-	log.Info("Telegram 消息发送成功")
 	return nil
 }
-
-// SendThread sends a thread notification
-func (t *TelegramNotifier) SendThread(thread *database.Thread, aiDescription string) error {
-	message := utils.FormatThreadMessage(thread, aiDescription)
-	return t.Send(message)
-}
-
-// SendComment sends a comment notification
-func (t *TelegramNotifier) SendComment(thread *database.Thread, comment *database.Comment, aiDescription string) error {
-	message := utils.FormatCommentMessage(thread, comment, aiDescription)
-	return t.Send(message)
-}
@@ -0,0 +1,68 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Cancellable Notifier Waits"
+//   Timestamp: "2025-12-19T10:10:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Rate limit spacing and Retry-After slept up to a minute and held up Stop"
+//   Principle_Applied: "Aether-Engineering-SOLID-S"
+//   Quality_Check: "Waits end as soon as the notifier is closed; the send fails and the outbox retries it"
+// }}
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrClosed is returned by a send that was waiting when its notifier was closed
+var ErrClosed = errors.New("通知器已关闭")
+
+// closer lets the waits of a notifier, such as rate limit spacing and
+// Retry-After, be cut short by Close. Requests made with ctx are cancelled too.
+type closer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newCloser() *closer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &closer{ctx: ctx, cancel: cancel}
+}
+
+// Close ends the waits and requests of sends in progress and of later sends
+func (c *closer) Close() error {
+	c.cancel()
+	return nil
+}
+
+// sleep waits for d, or returns ErrClosed when the notifier is closed first
+func (c *closer) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.ctx.Done():
+		return ErrClosed
+	}
+}
+
+// postJSON posts a JSON body with client, cancelled by Close with ErrClosed
+func (c *closer) postJSON(client *http.Client, url string, data []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil && c.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	return resp, err
+}
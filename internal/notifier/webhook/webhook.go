@@ -0,0 +1,152 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Generic Webhook Bodies"
+//   Timestamp: "2025-12-18T14:30:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "A GET with {message} in the URL cannot reach webhook APIs expecting POST bodies, headers or signatures"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, Template Method"
+//   Quality_Check: "Body templates validated in config against sample data; JSON bodies checked to be valid JSON"
+// }}
+
+// Package webhook renders the request bodies of custom webhook notifications,
+// which are Go text/template templates over Data, e.g.
+//
+//	{"title": {{json .Thread.Title}}, "url": {{json .Thread.Link}}, "summary": {{json .AISummary}}}
+//
+// It is a leaf package so that the config can validate templates.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter/prompt"
+)
+
+// Body types of webhook requests
+const (
+	BodyJSON = "json" // application/json, the rendered body must be valid JSON
+	BodyForm = "form" // application/x-www-form-urlencoded, escape values with urlquery
+	BodyText = "text" // text/plain
+)
+
+// DefaultSignatureHeader carries the HMAC signature when no header is configured
+const DefaultSignatureHeader = "X-Signature-256"
+
+// Data is the information available to body templates. Thread and Comment
+// are never nil: Comment is empty for thread notifications, and both are
+// empty for plain messages such as the test message.
+type Data struct {
+	Kind      string // "thread", "comment" or "message"
+	Text      string // the plain text notification, as sent by the legacy GET mode
+	AISummary string // AI summary, empty without AI filtering
+	Thread    *database.Thread
+	Comment   *database.Comment
+}
+
+// sample is rendered when a template is parsed to catch unknown fields
+var sample = Data{
+	Kind:      "comment",
+	Text:      "Sample VPS Offer\nRestocked",
+	AISummary: "补货",
+	Thread: &database.Thread{
+		Domain:      "lowendtalk.com",
+		Category:    "Offers",
+		Title:       "Sample VPS Offer",
+		Link:        "https://lowendtalk.com/discussion/1/sample-vps-offer",
+		Description: "1 vCPU, 1GB RAM, 20GB SSD for $10/year",
+		Creator:     "provider",
+		PubDate:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+	Comment: &database.Comment{
+		CommentID: "lowendtalk.com_1",
+		ThreadURL: "https://lowendtalk.com/discussion/1/sample-vps-offer",
+		Author:    "provider",
+		Role:      "Provider",
+		Message:   "Restocked",
+		CreatedAt: time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+		URL:       "https://lowendtalk.com/discussion/comment/1/#Comment_1",
+	},
+}
+
+// funcs extends the prompt template functions with json, which encodes a
+// value as a JSON literal, e.g. {{json .Comment.Message}}
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func init() {
+	for name, fn := range prompt.Funcs {
+		funcs[name] = fn
+	}
+}
+
+// ContentType returns the Content-Type header of a body type
+func ContentType(bodyType string) string {
+	switch bodyType {
+	case BodyForm:
+		return "application/x-www-form-urlencoded"
+	case BodyText:
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// Template is a parsed body template
+type Template struct {
+	bodyType string
+	tmpl     *template.Template
+}
+
+// Parse parses a body template and checks that it renders, and for JSON
+// bodies that the result is valid JSON
+func Parse(text, bodyType string) (*Template, error) {
+	tmpl, err := template.New("webhook").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("请求体模板语法错误: %w", err)
+	}
+
+	t := &Template{bodyType: bodyType, tmpl: tmpl}
+	if _, err := t.Render(sample); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render renders the body for data
+func (t *Template) Render(data Data) ([]byte, error) {
+	if data.Thread == nil {
+		data.Thread = &database.Thread{}
+	}
+	if data.Comment == nil {
+		data.Comment = &database.Comment{}
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染请求体模板失败: %w", err)
+	}
+	if t.bodyType == BodyJSON && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("请求体不是有效的 JSON，字符串请使用 json 函数输出，如 {{json .Thread.Title}}")
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the signature header value of a body: "sha256=" followed by
+// the hex HMAC-SHA256 of the body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
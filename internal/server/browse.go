@@ -0,0 +1,228 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "Thread And Comment Browsing API"
+//   Timestamp: "2025-12-09T10:40:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Collected threads and comments were only reachable through raw DB access"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, RESTful API"
+//   Quality_Check: "Paginated read-only endpoints with validated filters for both backends"
+// }}
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pagination reads ?page and ?page_size, clamped to sane bounds
+func pagination(c *gin.Context) (page, pageSize int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// parseTimeParam parses an RFC3339 timestamp or a YYYY-MM-DD date (UTC midnight)
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s 必须是 RFC3339 时间或 YYYY-MM-DD 日期", name)
+}
+
+// parseDateRange reads ?since and ?until
+func parseDateRange(c *gin.Context) (since, until time.Time, err error) {
+	if since, err = parseTimeParam(c, "since"); err != nil {
+		return
+	}
+	until, err = parseTimeParam(c, "until")
+	return
+}
+
+// validStatuses are the processing statuses accepted by ?status
+var validStatuses = map[string]bool{
+	database.StatusPending:         true,
+	database.StatusTooOld:          true,
+	database.StatusMuted:           true,
+	database.StatusKeywordFiltered: true,
+	database.StatusAIRejected:      true,
+	database.StatusAIHeld:          true,
+	database.StatusAIFailed:        true,
+	database.StatusUnrouted:        true,
+	database.StatusNotified:        true,
+	database.StatusQueued:          true,
+}
+
+// parseStatusParam reads ?status
+func parseStatusParam(c *gin.Context) (string, error) {
+	status := c.Query("status")
+	if status != "" && !validStatuses[status] {
+		return "", fmt.Errorf("未知的状态: %s", status)
+	}
+	return status, nil
+}
+
+// handleListThreads lists stored threads, e.g. ?domain=lowendtalk&creator=foo&since=2025-12-01
+func (s *Server) handleListThreads(c *gin.Context) {
+	since, until, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	status, err := parseStatusParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	page, pageSize := pagination(c)
+	threads, total, err := s.db.ListThreads(database.ThreadQuery{
+		Domain:   c.Query("domain"),
+		Category: c.Query("category"),
+		Creator:  c.Query("creator"),
+		Status:   status,
+		Search:   c.Query("q"),
+		Since:    since,
+		Until:    until,
+		Offset:   (page - 1) * pageSize,
+		Limit:    pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("查询线程失败: %v", err),
+		})
+		return
+	}
+
+	if threads == nil {
+		threads = []*database.Thread{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"threads":   threads,
+	})
+}
+
+// handleListThreadComments lists the stored comments of one thread
+func (s *Server) handleListThreadComments(c *gin.Context) {
+	thread, err := s.db.FindThreadByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("查询线程失败: %v", err),
+		})
+		return
+	}
+	if thread == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "线程不存在",
+		})
+		return
+	}
+
+	s.listComments(c, thread.Link, thread)
+}
+
+// handleListComments lists stored comments, e.g. ?author=foo&role=provider&q=coupon
+func (s *Server) handleListComments(c *gin.Context) {
+	s.listComments(c, "", nil)
+}
+
+// listComments runs a comment query from the request parameters, optionally scoped to one thread
+func (s *Server) listComments(c *gin.Context, threadURL string, thread *database.Thread) {
+	since, until, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	status, err := parseStatusParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	page, pageSize := pagination(c)
+	comments, total, err := s.db.ListComments(database.CommentQuery{
+		ThreadURL: threadURL,
+		Domain:    c.Query("domain"),
+		Author:    c.Query("author"),
+		Role:      c.Query("role"),
+		Status:    status,
+		Search:    c.Query("q"),
+		Since:     since,
+		Until:     until,
+		Offset:    (page - 1) * pageSize,
+		Limit:     pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("查询评论失败: %v", err),
+		})
+		return
+	}
+
+	if comments == nil {
+		comments = []*database.Comment{}
+	}
+
+	response := gin.H{
+		"status":    "success",
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"comments":  comments,
+	}
+	if thread != nil {
+		response["thread"] = thread
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,129 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Prompt Preview API"
+//   Timestamp: "2025-12-17T10:00:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Prompt templates are hard to write blind; users need the exact messages the provider receives"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, RESTful API"
+//   Quality_Check: "Renders stored records with the monitor's data mapping; unsaved templates previewed without saving"
+// }}
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+	"github.com/imhuimie/let-monitor-go/internal/filter"
+	"github.com/imhuimie/let-monitor-go/internal/filter/prompt"
+)
+
+// handlePromptPreview renders the AI prompt for a stored thread or comment.
+// The body names one of thread_id or comment_id; template, when set, is
+// rendered instead of the saved prompt so edits can be checked before saving.
+// It returns the system message and the content sent to the provider.
+func (s *Server) handlePromptPreview(c *gin.Context) {
+	var req struct {
+		ThreadID  string  `json:"thread_id"`
+		CommentID string  `json:"comment_id"`
+		Template  *string `json:"template"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || (req.ThreadID == "") == (req.CommentID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "无效的请求数据，需要 thread_id 或 comment_id 之一",
+		})
+		return
+	}
+
+	cfg := s.configMgr.Get()
+	kind := "thread"
+	text := cfg.ThreadPrompt
+	var data prompt.Data
+	var content string
+
+	if req.ThreadID != "" {
+		thread, err := s.db.FindThreadByID(req.ThreadID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("查询线程失败: %v", err),
+			})
+			return
+		}
+		if thread == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "线程不存在",
+			})
+			return
+		}
+		data = filter.ThreadPromptData(thread)
+		content = thread.Description
+	} else {
+		comment, err := s.db.FindComment(req.CommentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("查询评论失败: %v", err),
+			})
+			return
+		}
+		if comment == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "评论不存在",
+			})
+			return
+		}
+
+		thread, err := s.db.FindThread(comment.ThreadURL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("查询线程失败: %v", err),
+			})
+			return
+		}
+		if thread == nil {
+			// Thread no longer stored, render the comment without its context
+			thread = &database.Thread{Link: comment.ThreadURL}
+		}
+
+		kind = "comment"
+		text = cfg.CommentPrompt
+		data = filter.CommentPromptData(thread, comment)
+		content = comment.Message
+	}
+
+	if req.Template != nil {
+		text = *req.Template
+	}
+
+	tmpl, err := prompt.Parse(text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	rendered, err := tmpl.Render(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"kind":    kind,
+		"system":  filter.SystemPrompt(rendered, cfg.AIOutputMode),
+		"content": content,
+	})
+}
@@ -13,14 +13,18 @@ package server
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imhuimie/let-monitor-go/internal/config"
+	"github.com/imhuimie/let-monitor-go/internal/database"
 	"github.com/imhuimie/let-monitor-go/internal/filter"
+	"github.com/imhuimie/let-monitor-go/internal/metrics"
 	"github.com/imhuimie/let-monitor-go/internal/monitor"
 	"github.com/imhuimie/let-monitor-go/internal/notifier"
 	log "github.com/sirupsen/logrus"
@@ -29,17 +33,25 @@ import (
 //go:embed templates/*
 var templateFS embed.FS
 
+// configRevisionHeader carries the revision of the configuration returned
+// by GET /api/config, to be sent back as "revision" when saving it
+const configRevisionHeader = "X-Config-Revision"
+
+// processStart records when the process started, for uptime reporting
+var processStart = time.Now()
+
 // Server represents the web server
 type Server struct {
 	engine      *gin.Engine
 	server      *http.Server
 	configMgr   *config.Manager
 	monitor     *monitor.ForumMonitor
+	db          database.Database
 	accessToken string
 }
 
 // NewServer creates a new web server
-func NewServer(configMgr *config.Manager, mon *monitor.ForumMonitor, accessToken string, port string) *Server {
+func NewServer(configMgr *config.Manager, mon *monitor.ForumMonitor, db database.Database, accessToken string, port string) *Server {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
 	engine.Use(gin.Recovery())
@@ -49,6 +61,7 @@ func NewServer(configMgr *config.Manager, mon *monitor.ForumMonitor, accessToken
 		engine:      engine,
 		configMgr:   configMgr,
 		monitor:     mon,
+		db:          db,
 		accessToken: accessToken,
 	}
 
@@ -72,6 +85,9 @@ func (s *Server) setupRoutes() {
 	// Serve index page
 	s.engine.GET("/", s.handleIndex)
 
+	// Prometheus metrics (no auth required, like the health check)
+	s.engine.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// API routes
 	api := s.engine.Group("/api")
 	{
@@ -82,7 +98,32 @@ func (s *Server) setupRoutes() {
 		api.GET("/config", s.authMiddleware(), s.handleGetConfig)
 		api.POST("/config", s.authMiddleware(), s.handleUpdateConfig)
 		api.POST("/test-openai", s.authMiddleware(), s.handleTestOpenAI)
+		api.POST("/test-anthropic", s.authMiddleware(), s.handleTestAnthropic)
+		api.POST("/test-ollama", s.authMiddleware(), s.handleTestOllama)
 		api.POST("/test-telegram", s.authMiddleware(), s.handleTestTelegram)
+		api.POST("/test-discord", s.authMiddleware(), s.handleTestDiscord)
+		api.POST("/test-slack", s.authMiddleware(), s.handleTestSlack)
+		api.POST("/test-teams", s.authMiddleware(), s.handleTestTeams)
+
+		// Monitor schedule and control (auth required)
+		api.GET("/schedule", s.authMiddleware(), s.handleGetSchedule)
+		api.GET("/monitor/status", s.authMiddleware(), s.handleMonitorStatus)
+		api.POST("/monitor/pause", s.authMiddleware(), s.handlePauseMonitor)
+		api.POST("/monitor/resume", s.authMiddleware(), s.handleResumeMonitor)
+		api.POST("/monitor/check", s.authMiddleware(), s.handleCheckNow)
+
+		// Notification outbox endpoints (auth required)
+		api.GET("/outbox", s.authMiddleware(), s.handleListOutbox)
+		api.POST("/outbox/:id/retry", s.authMiddleware(), s.handleRetryOutbox)
+
+		// Stored thread and comment browsing (auth required)
+		api.GET("/threads", s.authMiddleware(), s.handleListThreads)
+		api.GET("/threads/:id/comments", s.authMiddleware(), s.handleListThreadComments)
+		api.GET("/comments", s.authMiddleware(), s.handleListComments)
+
+		// AI usage accounting and prompt preview (auth required)
+		api.GET("/ai/usage", s.authMiddleware(), s.handleAIUsage)
+		api.POST("/ai/prompt-preview", s.authMiddleware(), s.handlePromptPreview)
 	}
 }
 
@@ -115,21 +156,50 @@ func (s *Server) handleIndex(c *gin.Context) {
 	}
 }
 
-// handleHealth returns health status
+// handleHealth returns health status. It responds with 503 when the database
+// cannot be reached or the monitor is stopped or stalled.
 func (s *Server) handleHealth(c *gin.Context) {
-	uptime := time.Since(time.Now()) // This would need to track actual start time
+	uptime := time.Since(processStart)
+	health := s.monitor.Health()
+
+	status := "ok"
+	code := http.StatusOK
+
+	dbStatus := gin.H{"status": "connected"}
+	pingStart := time.Now()
+	if err := s.db.Ping(); err != nil {
+		dbStatus = gin.H{"status": "error", "error": err.Error()}
+		status = "error"
+		code = http.StatusServiceUnavailable
+	}
+	dbStatus["latency"] = time.Since(pingStart).Round(time.Microsecond).String()
+
+	monitorStatus := getMonitorStatus(s.monitor)
+	if monitorStatus == "stopped" {
+		status = "error"
+		code = http.StatusServiceUnavailable
+	} else if health.Stalled {
+		monitorStatus = "stalled"
+		status = "error"
+		code = http.StatusServiceUnavailable
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":   "ok",
-		"database": "connected",
-		"monitor":  getMonitorStatus(s.monitor),
-		"uptime":   uptime.String(),
+	c.JSON(code, gin.H{
+		"status":         status,
+		"database":       dbStatus,
+		"monitor":        monitorStatus,
+		"uptime":         uptime.Round(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"started_at":     processStart,
+		"last_cycle":     health.LastCycle,
+		"sources":        health.Sources,
 	})
 }
 
-// handleGetConfig returns the current configuration
+// handleGetConfig returns the current configuration, with its revision in
+// the X-Config-Revision header
 func (s *Server) handleGetConfig(c *gin.Context) {
-	cfg := s.configMgr.Get()
+	cfg, revision := s.configMgr.GetRevision()
 	if cfg == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "配置未加载",
@@ -137,13 +207,17 @@ func (s *Server) handleGetConfig(c *gin.Context) {
 		return
 	}
 
+	c.Header(configRevisionHeader, strconv.FormatUint(revision, 10))
 	c.JSON(http.StatusOK, cfg)
 }
 
-// handleUpdateConfig updates the configuration
+// handleUpdateConfig updates the configuration. A request carrying the
+// revision it was edited from is rejected with 409 when the configuration
+// has changed since, e.g. through a telegram bot command.
 func (s *Server) handleUpdateConfig(c *gin.Context) {
 	var requestBody struct {
-		Config *config.Config `json:"config"`
+		Config   *config.Config `json:"config"`
+		Revision *uint64        `json:"revision"`
 	}
 
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
@@ -163,18 +237,32 @@ func (s *Server) handleUpdateConfig(c *gin.Context) {
 		return
 	}
 
-	// Validate configuration
-	if err := requestBody.Config.Validate(); err != nil {
-		log.Warnf("配置验证失败: %v", err)
+	// Save configuration, serialized with the bot's updates
+	change := func(cfg *config.Config) bool {
+		*cfg = *requestBody.Config
+		return true
+	}
+	var err error
+	if requestBody.Revision != nil {
+		_, err = s.configMgr.UpdateAt(*requestBody.Revision, change)
+	} else {
+		_, err = s.configMgr.Update(change)
+	}
+	switch {
+	case errors.Is(err, config.ErrStale):
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, config.ErrInvalid):
+		log.Warnf("%v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
-			"message": fmt.Sprintf("配置验证失败: %v", err),
+			"message": err.Error(),
 		})
 		return
-	}
-
-	// Save configuration
-	if err := s.configMgr.Save(requestBody.Config); err != nil {
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": fmt.Sprintf("保存配置失败: %v", err),
@@ -192,8 +280,27 @@ func (s *Server) handleUpdateConfig(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Config updated",
+		"status":   "success",
+		"message":  "Config updated",
+		"schedule": s.monitor.Schedule(),
+	})
+}
+
+// handleGetSchedule returns the effective schedule of every monitored source
+func (s *Server) handleGetSchedule(c *gin.Context) {
+	sources := s.monitor.Schedule()
+
+	var nextRun *time.Time
+	for i := range sources {
+		if nextRun == nil || sources[i].NextRun.Before(*nextRun) {
+			nextRun = &sources[i].NextRun
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"next_run": nextRun,
+		"sources":  sources,
 	})
 }
 
@@ -214,13 +321,53 @@ func (s *Server) handleTestOpenAI(c *gin.Context) {
 	}
 
 	// Create OpenAI filter with test parameters
-	openaiFilter := filter.NewOpenAIFilter(testReq.APIUrl, testReq.APIKey, testReq.Model)
+	testAIFilter(c, filter.NewOpenAIFilter(testReq.APIUrl, testReq.APIKey, testReq.Model, config.AIOutputText))
+}
+
+// handleTestAnthropic tests Anthropic Messages API configuration
+func (s *Server) handleTestAnthropic(c *gin.Context) {
+	var testReq struct {
+		APIUrl string `json:"api_url"` // empty uses the public endpoint
+		APIKey string `json:"api_key"`
+		Model  string `json:"model"`
+	}
+
+	if err := c.ShouldBindJSON(&testReq); err != nil || testReq.APIKey == "" || testReq.Model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "无效的请求数据",
+		})
+		return
+	}
+
+	testAIFilter(c, filter.NewAnthropicFilter(testReq.APIUrl, testReq.APIKey, testReq.Model, 0, config.AIOutputText))
+}
+
+// handleTestOllama tests Ollama configuration
+func (s *Server) handleTestOllama(c *gin.Context) {
+	var testReq struct {
+		URL   string `json:"url"` // empty uses http://localhost:11434
+		Model string `json:"model"`
+	}
+
+	if err := c.ShouldBindJSON(&testReq); err != nil || testReq.Model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "无效的请求数据",
+		})
+		return
+	}
+
+	testAIFilter(c, filter.NewOllamaFilter(testReq.URL, testReq.Model, config.AIOutputText))
+}
 
+// testAIFilter sends a test message through an AI filter and reports the reply
+func testAIFilter(c *gin.Context, aiFilter filter.AIFilterInterface) {
 	// Test with a simple message
 	testContent := "Hello, this is a test message."
 	testPrompt := "Please respond with 'Test successful' if you receive this message."
 
-	result, err := openaiFilter.Filter(testContent, testPrompt)
+	result, err := aiFilter.Filter(testContent, testPrompt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -251,14 +398,23 @@ func (s *Server) handleTestTelegram(c *gin.Context) {
 		return
 	}
 
+	// chat_id may name a forum topic, see config.ParseTelegramTarget
+	target, err := config.ParseTelegramTarget(testReq.ChatID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// Create Telegram notifier with test parameters
-	telegramNotifier := notifier.NewTelegramNotifier(testReq.BotToken, testReq.ChatID)
+	telegramNotifier := notifier.NewTelegramNotifier(testReq.BotToken, []config.TelegramTarget{target}, notifier.TelegramOptions{})
 
 	// Test with a simple message
 	testMessage := "🔔 这是来自 Let-Monitor-Go 的测试消息\n\n如果您收到此消息，说明 Telegram 配置正确！"
 
-	err := telegramNotifier.Send(testMessage)
-	if err != nil {
+	if err := telegramNotifier.Send(testMessage); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": fmt.Sprintf("发送测试消息失败: %v", err),
@@ -272,6 +428,191 @@ func (s *Server) handleTestTelegram(c *gin.Context) {
 	})
 }
 
+// handleTestDiscord tests a Discord webhook
+func (s *Server) handleTestDiscord(c *gin.Context) {
+	if webhookURL, ok := bindWebhookURL(c); ok {
+		testChatWebhook(c, config.ChannelConfig{Type: "discord", DiscordWebhook: webhookURL}, "Discord")
+	}
+}
+
+// handleTestSlack tests a Slack incoming webhook
+func (s *Server) handleTestSlack(c *gin.Context) {
+	if webhookURL, ok := bindWebhookURL(c); ok {
+		testChatWebhook(c, config.ChannelConfig{Type: "slack", SlackWebhook: webhookURL}, "Slack")
+	}
+}
+
+// handleTestTeams tests a Microsoft Teams webhook
+func (s *Server) handleTestTeams(c *gin.Context) {
+	if webhookURL, ok := bindWebhookURL(c); ok {
+		testChatWebhook(c, config.ChannelConfig{Type: "teams", TeamsWebhook: webhookURL}, "Teams")
+	}
+}
+
+// bindWebhookURL reads {"webhook_url": ...} from the request, answering 400
+// when the body is invalid
+func bindWebhookURL(c *gin.Context) (string, bool) {
+	var testReq struct {
+		WebhookURL string `json:"webhook_url"`
+	}
+
+	if err := c.ShouldBindJSON(&testReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "无效的请求数据",
+		})
+		return "", false
+	}
+	return testReq.WebhookURL, true
+}
+
+// testChatWebhook validates a chat platform channel and sends a test message through it
+func testChatWebhook(c *gin.Context, ch config.ChannelConfig, platform string) {
+	if err := ch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ntf, err := notifier.NewChannelNotifier(ch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	testMessage := "🔔 这是来自 Let-Monitor-Go 的测试消息\n\n如果您收到此消息，说明 " + platform + " 配置正确！"
+	if err := ntf.Send(testMessage); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("发送测试消息失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "测试消息发送成功，请检查您的 " + platform,
+	})
+}
+
+// handleMonitorStatus returns whether the monitor is paused and which checks are in progress
+func (s *Server) handleMonitorStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"monitor": s.monitor.Status(),
+	})
+}
+
+// handlePauseMonitor pauses scheduled checks
+func (s *Server) handlePauseMonitor(c *gin.Context) {
+	s.monitor.Pause()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "监控已暂停",
+		"monitor": s.monitor.Status(),
+	})
+}
+
+// handleResumeMonitor resumes scheduled checks
+func (s *Server) handleResumeMonitor(c *gin.Context) {
+	s.monitor.Resume()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "监控已恢复",
+		"monitor": s.monitor.Status(),
+	})
+}
+
+// handleCheckNow triggers an immediate check of all sources or of a single source
+func (s *Server) handleCheckNow(c *gin.Context) {
+	var checkReq struct {
+		Source string `json:"source"`
+	}
+
+	// The body is optional; an empty body checks every source
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&checkReq); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "无效的请求数据",
+			})
+			return
+		}
+	}
+
+	if err := s.monitor.CheckNow(checkReq.Source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("触发检查失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "已触发检查",
+		"monitor": s.monitor.Status(),
+	})
+}
+
+// handleListOutbox lists notification outbox entries, e.g. ?status=dead
+func (s *Server) handleListOutbox(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != database.OutboxPending && status != database.OutboxSent && status != database.OutboxDead {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "status 必须是 'pending', 'sent' 或 'dead'",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	entries, err := s.db.ListOutbox(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("查询通知队列失败: %v", err),
+		})
+		return
+	}
+
+	if entries == nil {
+		entries = []*database.OutboxEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"entries": entries,
+	})
+}
+
+// handleRetryOutbox moves an outbox entry back to pending so it is retried immediately
+func (s *Server) handleRetryOutbox(c *gin.Context) {
+	if err := s.db.RequeueOutbox(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("重新排队失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "已重新加入发送队列",
+	})
+}
+
 // authMiddleware checks for valid access token
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -321,8 +662,11 @@ func LoggerMiddleware() gin.HandlerFunc {
 
 // getMonitorStatus returns the monitor status
 func getMonitorStatus(mon *monitor.ForumMonitor) string {
-	if mon.IsRunning() {
-		return "running"
+	if !mon.IsRunning() {
+		return "stopped"
+	}
+	if mon.IsPaused() {
+		return "paused"
 	}
-	return "stopped"
+	return "running"
 }
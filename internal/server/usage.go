@@ -0,0 +1,76 @@
+// {{RIPER-5-Enhanced:
+//   Action: "Added"
+//   Task_ID: "AI Usage Accounting API"
+//   Timestamp: "2025-12-16T10:05:00Z"
+//   Authoring_Role: "LD"
+//   Analysis_Performed: "Stored AI usage needed a daily view and the state of today's budget"
+//   Principle_Applied: "Aether-Engineering-SOLID-S, RESTful API"
+//   Quality_Check: "Days clamped to one year; today's totals compared with the configured budgets"
+// }}
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imhuimie/let-monitor-go/internal/database"
+)
+
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+// handleAIUsage returns the AI usage per UTC day, provider and model for the
+// last ?days days, and today's totals against the daily budgets
+func (s *Server) handleAIUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultUsageDays)))
+	if err != nil || days < 1 {
+		days = defaultUsageDays
+	}
+	if days > maxUsageDays {
+		days = maxUsageDays
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	summaries, err := s.db.SummarizeAIUsage(today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("查询 AI 用量失败: %v", err),
+		})
+		return
+	}
+	if summaries == nil {
+		summaries = []*database.AIUsageSummary{}
+	}
+
+	var tokens int64
+	var cost float64
+	date := today.Format("2006-01-02")
+	for _, summary := range summaries {
+		if summary.Date == date {
+			tokens += summary.PromptTokens + summary.CompletionTokens
+			cost += summary.Cost
+		}
+	}
+
+	cfg := s.configMgr.Get()
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"days":   summaries,
+		"today": gin.H{
+			"date":         date,
+			"tokens":       tokens,
+			"cost":         cost,
+			"token_budget": cfg.AIDailyTokenBudget,
+			"cost_budget":  cfg.AIDailyCostBudget,
+			"over_budget": (cfg.AIDailyTokenBudget > 0 && tokens >= cfg.AIDailyTokenBudget) ||
+				(cfg.AIDailyCostBudget > 0 && cost >= cfg.AIDailyCostBudget),
+		},
+	})
+}
@@ -28,12 +28,9 @@ func FormatThreadMessage(thread *database.Thread, aiDescription string) string {
 
 	if aiDescription != "" {
 		// Truncate AI description to 200 characters
-		if len(aiDescription) > 200 {
-			sb.WriteString(fmt.Sprintf("%s...\n\n", aiDescription[:200]))
-		} else {
-			sb.WriteString(fmt.Sprintf("%s\n\n", aiDescription))
-		}
+		sb.WriteString(fmt.Sprintf("%s\n\n", truncate(aiDescription, 200)))
 	}
+	writeVerdict(&sb, thread.Decision)
 
 	sb.WriteString(thread.Link)
 
@@ -49,22 +46,44 @@ func FormatCommentMessage(thread *database.Thread, comment *database.Comment, ai
 	sb.WriteString(fmt.Sprintf("时间：%s\n\n", comment.CreatedAt.Format("2006/01/02 15:04")))
 
 	// Truncate message to 200 characters
-	if len(comment.Message) > 200 {
-		sb.WriteString(fmt.Sprintf("%s...\n\n", comment.Message[:200]))
-	} else {
-		sb.WriteString(fmt.Sprintf("%s\n\n", comment.Message))
-	}
+	sb.WriteString(fmt.Sprintf("%s\n\n", truncate(comment.Message, 200)))
 
 	if aiDescription != "" {
 		// Truncate AI description to 200 characters
-		if len(aiDescription) > 200 {
-			sb.WriteString(fmt.Sprintf("%s...\n\n", aiDescription[:200]))
-		} else {
-			sb.WriteString(fmt.Sprintf("%s\n\n", aiDescription))
-		}
+		sb.WriteString(fmt.Sprintf("%s\n\n", truncate(aiDescription, 200)))
 	}
+	writeVerdict(&sb, comment.Decision)
 
 	sb.WriteString(comment.URL)
 
 	return sb.String()
 }
+
+// truncate cuts s to n characters, never inside a multi-byte character
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// writeVerdict adds the structured fields of a JSON AI verdict; the summary
+// itself is passed as the AI description
+func writeVerdict(sb *strings.Builder, decision *database.Decision) {
+	if decision == nil || decision.AIVerdict == nil {
+		return
+	}
+
+	verdict := decision.AIVerdict
+	if verdict.CheapestPlan == "" && verdict.Coupon == "" {
+		return
+	}
+	if verdict.CheapestPlan != "" {
+		sb.WriteString(fmt.Sprintf("最低价套餐：%s\n", verdict.CheapestPlan))
+	}
+	if verdict.Coupon != "" {
+		sb.WriteString(fmt.Sprintf("优惠码：%s\n", verdict.Coupon))
+	}
+	sb.WriteString("\n")
+}